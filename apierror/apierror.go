@@ -0,0 +1,127 @@
+// Package apierror defines the errors returned by the meson API.
+//
+// Every failure of a call to the framework is an *Error, which tells the object, the method
+// and a machine-readable Code. Use errors.Is with the sentinels to test a kind of failure:
+//
+//	if err := win.Focus(); errors.Is(err, apierror.ErrObjectDestroyed) {
+//		// the window has already been closed.
+//	}
+package apierror
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-meson/meson/internal/binding"
+	"github.com/go-meson/meson/object"
+)
+
+// Code is a machine-readable kind of failure.
+type Code string
+
+const (
+	// CodeUnknown is a failure which the framework does not classify.
+	CodeUnknown Code = "unknown"
+	// CodeNotReady means the meson API is not ready yet.
+	CodeNotReady Code = "not_ready"
+	// CodeObjectDestroyed means the target object has already been destroyed.
+	CodeObjectDestroyed Code = "object_destroyed"
+	// CodeUnsupported means the method is not supported on the platform or the framework.
+	CodeUnsupported Code = "unsupported"
+	// CodeInvalidArgument means an argument is rejected.
+	CodeInvalidArgument Code = "invalid_argument"
+	// CodeTimeout means the framework did not answer in time.
+	CodeTimeout Code = "timeout"
+)
+
+// Sentinels of each Code, for errors.Is.
+var (
+	ErrNotReady        = errors.New("meson api is not ready yet")
+	ErrObjectDestroyed = errors.New("object is destroyed")
+	ErrUnsupported     = errors.New("not supported")
+	ErrInvalidArgument = errors.New("invalid argument")
+	ErrTimeout         = errors.New("timeout")
+)
+
+var sentinels = map[Code]error{
+	CodeNotReady:        ErrNotReady,
+	CodeObjectDestroyed: ErrObjectDestroyed,
+	CodeUnsupported:     ErrUnsupported,
+	CodeInvalidArgument: ErrInvalidArgument,
+	CodeTimeout:         ErrTimeout,
+}
+
+// Error is a failure of the meson API.
+type Error struct {
+	ObjType object.ObjectType // type of the target object
+	ID      int64             // id of the target object, or binding.ObjStaticID for class methods
+	Method  string            // method of the failed call, if any
+	Code    Code
+	Message string // message from the framework, if any
+	Err     error  // underlying cause, if any
+}
+
+// New creates an Error of code about the object of objType and id.
+func New(code Code, objType object.ObjectType, id int64, method string, message string) *Error {
+	return &Error{ObjType: objType, ID: id, Method: method, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	target := typeName(e.ObjType)
+	if e.ID != binding.ObjStaticID {
+		target = fmt.Sprintf("%s#%d", target, e.ID)
+	}
+	if e.Method != "" {
+		target += "." + e.Method
+	}
+	msg := e.Message
+	if msg == "" && e.Err != nil {
+		msg = e.Err.Error()
+	}
+	if msg == "" {
+		if s, ok := sentinels[e.Code]; ok {
+			msg = s.Error()
+		} else {
+			msg = string(e.Code)
+		}
+	}
+	return fmt.Sprintf("meson: %s: %s", target, msg)
+}
+
+// Is reports whether target is the sentinel of the code of e.
+func (e *Error) Is(target error) bool {
+	s, ok := sentinels[e.Code]
+	return ok && s == target
+}
+
+// Unwrap returns the underlying cause.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// CodeOf returns the Code of err, or CodeUnknown if err is not an *Error.
+func CodeOf(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return CodeUnknown
+}
+
+func typeName(t object.ObjectType) string {
+	switch t {
+	case binding.ObjApp:
+		return "app"
+	case binding.ObjWindow:
+		return "window"
+	case binding.ObjSession:
+		return "session"
+	case binding.ObjWebContents:
+		return "webcontents"
+	case binding.ObjMenu:
+		return "menu"
+	case binding.ObjDialog:
+		return "dialog"
+	}
+	return fmt.Sprintf("object(%d)", t)
+}
@@ -2,11 +2,17 @@
 package app
 
 import (
+	"context"
+	"log"
+
 	"github.com/go-meson/meson/event"
 	"github.com/go-meson/meson/internal/binding"
+	"github.com/go-meson/meson/internal/codec"
 	"github.com/go-meson/meson/internal/command"
 	evt "github.com/go-meson/meson/internal/event"
 	"github.com/go-meson/meson/internal/object"
+	obj "github.com/go-meson/meson/object"
+	"github.com/go-meson/meson/window"
 )
 
 // App is your application’s instance.
@@ -24,24 +30,226 @@ func newApp() *App {
 	return app
 }
 
+func init() {
+	// set up before the application code runs, not to race with its calls.
+	command.AddReadyHook(func(ctx context.Context) {
+		if err := watchLifecycle(ctx); err != nil {
+			log.Printf("watch application lifecycle fail: %v", err)
+		}
+		if err := applyPaths(); err != nil {
+			log.Printf("set application paths fail: %v", err)
+		}
+	})
+}
+
+//------------------------------------------------------------------------
+// Lifecycle
+
+// State is a phase of the application lifecycle: starting, ready, quitting and exited.
+type State = command.State
+
+// States of the application lifecycle.
+const (
+	StateStarting = command.StateStarting
+	StateReady    = command.StateReady
+	StateQuitting = command.StateQuitting
+	StateExited   = command.StateExited
+)
+
+// CurrentState returns the current state of the application.
+func CurrentState() State {
+	return command.CurrentState()
+}
+
+// WaitState waits until the application reaches state s, or ctx is done.
+func WaitState(ctx context.Context, s State) error {
+	return command.WaitState(ctx, s)
+}
+
+// Context returns the root context of the application, which is cancelled when the application starts quitting.
+// Background goroutines should stop when it is done.
+func Context() context.Context {
+	return command.RootContext()
+}
+
+// quittingItem moves the application to quitting state unless the event is prevented.
+type quittingItem struct{}
+
+func (quittingItem) Call(o obj.ObjectRef, arg codec.Raw) (bool, error) {
+	return false, nil
+}
+
+func (quittingItem) Observe(o obj.ObjectRef, arg codec.Raw, prevented bool) {
+	if !prevented {
+		command.SetState(command.StateQuitting)
+	}
+}
+
+// watchLifecycle follows the quit events of the framework.
+func watchLifecycle(ctx context.Context) error {
+	if _, err := evt.AddCallbackContext(ctx, &app.Object, "will-quit", quittingItem{}); err != nil {
+		return err
+	}
+	_, err := evt.AddCallbackContext(ctx, &app.Object, "quit", quittingItem{})
+	return err
+}
+
 //------------------------------------------------------------------------
 // Methods
 
 // Exit exit meson application with exit code.
+//
+// Unlike Quit, it does not emit the quit events, but the root context is cancelled.
 func Exit(code int) {
+	command.SetState(command.StateQuitting)
 	cmd := command.MakeCallCommand(binding.ObjApp, binding.ObjStaticID, "exit", code)
 	if err := command.PostMessage(&cmd); err != nil {
 		panic(err)
 	}
 }
 
+// Quit tries to quit the application.
+//
+// The framework emits 'before-quit', closes all windows, and emits 'will-quit'.
+// Any handler of them can prevent quitting. Otherwise the application starts quitting,
+// the root context is cancelled, and 'quit' is emitted with the exit code.
+func Quit() error {
+	return command.Post(&app.Object, "quit")
+}
+
 //------------------------------------------------------------------------
 // Callbacks
 
+// OnReady set 'ready' event handler.
+//
+// 'ready' emitted once the framework is ready. The callback is called at once
+// in another goroutine if the framework is ready already.
+func OnReady(callback func()) (*event.Subscription, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		if command.WaitState(ctx, command.StateReady) == nil {
+			callback()
+		}
+	}()
+	return object.NewSubscription(cancel), nil
+}
+
 // OnWindowCloseAll set 'window-all-closed' event handler.
 //
 // 'window-all-closed' emitted when all windows have been closed.
-func OnWindowCloseAll(callback event.CommonCallbackHandler) error {
+func OnWindowCloseAll(callback event.CommonCallbackHandler) (*event.Subscription, error) {
 	const en = "window-all-closed"
 	return evt.AddCallback(&app.Object, en, evt.CommonCallbackItem{F: callback})
 }
+
+// OnBeforeQuit set 'before-quit' event handler.
+//
+// 'before-quit' emitted before the application starts closing its windows.
+// Returning true prevents quitting.
+func OnBeforeQuit(callback event.CommonPreventableCallbackHandler) (*event.Subscription, error) {
+	const en = "before-quit"
+	return evt.AddCallback(&app.Object, en, evt.CommonPreventableCallbackItem{F: callback})
+}
+
+// OnWillQuit set 'will-quit' event handler.
+//
+// 'will-quit' emitted when all windows have been closed and the application will quit.
+// Returning true prevents quitting.
+func OnWillQuit(callback event.CommonPreventableCallbackHandler) (*event.Subscription, error) {
+	const en = "will-quit"
+	return evt.AddCallback(&app.Object, en, evt.CommonPreventableCallbackItem{F: callback})
+}
+
+// OnQuit set 'quit' event handler.
+//
+// 'quit' emitted with the exit code when the application is quitting.
+func OnQuit(callback func(exitCode int)) (*event.Subscription, error) {
+	const en = "quit"
+	return event.Subscribe(app, en, func(sender obj.ObjectRef, p struct {
+		ExitCode int `json:"exitCode"`
+	}) {
+		callback(p.ExitCode)
+	})
+}
+
+// OnActivate set 'activate' event handler.
+//
+// 'activate' emitted when the application is activated, such as by clicking its dock icon on macOS.
+// hasVisibleWindows reports whether the application has visible windows.
+func OnActivate(callback func(hasVisibleWindows bool)) (*event.Subscription, error) {
+	const en = "activate"
+	return event.Subscribe(app, en, func(sender obj.ObjectRef, p struct {
+		HasVisibleWindows bool `json:"hasVisibleWindows"`
+	}) {
+		callback(p.HasVisibleWindows)
+	})
+}
+
+// OnOpenFile set 'open-file' event handler.
+//
+// 'open-file' emitted when the user wants to open the file of path with the application,
+// such as by dropping it on the dock icon on macOS.
+// Returning true tells the framework that the file is handled.
+func OnOpenFile(callback func(path string) bool) (*event.Subscription, error) {
+	const en = "open-file"
+	return event.Subscribe(app, en, func(sender obj.ObjectRef, p struct {
+		Path string `json:"path"`
+	}) bool {
+		return callback(p.Path)
+	})
+}
+
+// OnOpenURL set 'open-url' event handler.
+//
+// 'open-url' emitted when the user wants to open url with the application,
+// which is registered as the handler of its scheme.
+func OnOpenURL(callback func(url string)) (*event.Subscription, error) {
+	const en = "open-url"
+	return event.Subscribe(app, en, func(sender obj.ObjectRef, p struct {
+		URL string `json:"url"`
+	}) {
+		callback(p.URL)
+	})
+}
+
+// windowPayload is the payload of the events about a window.
+type windowPayload struct {
+	Window object.Ref `json:"window"`
+}
+
+// OnBrowserWindowCreated set 'browser-window-created' event handler.
+//
+// 'browser-window-created' emitted when a window is created, including the ones opened by pages.
+func OnBrowserWindowCreated(callback func(w *window.Window)) (*event.Subscription, error) {
+	const en = "browser-window-created"
+	return event.Subscribe(app, en, func(sender obj.ObjectRef, p windowPayload) {
+		if p.Window.Type == binding.ObjWindow {
+			callback(window.Attach(p.Window.ID))
+		}
+	})
+}
+
+// OnBrowserWindowFocus set 'browser-window-focus' event handler.
+//
+// 'browser-window-focus' emitted when a window gets focused.
+func OnBrowserWindowFocus(callback func(w *window.Window)) (*event.Subscription, error) {
+	const en = "browser-window-focus"
+	return subscribeWindowEvent(en, callback)
+}
+
+// OnBrowserWindowBlur set 'browser-window-blur' event handler.
+//
+// 'browser-window-blur' emitted when a window loses focus.
+func OnBrowserWindowBlur(callback func(w *window.Window)) (*event.Subscription, error) {
+	const en = "browser-window-blur"
+	return subscribeWindowEvent(en, callback)
+}
+
+// subscribeWindowEvent subscribes the event about a live window. Events about unknown windows are ignored.
+func subscribeWindowEvent(name string, callback func(w *window.Window)) (*event.Subscription, error) {
+	return event.Subscribe(app, name, func(sender obj.ObjectRef, p windowPayload) {
+		if w, ok := p.Window.Object.(*window.Window); ok {
+			callback(w)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/go-meson/meson/internal/binding"
+	"github.com/go-meson/meson/internal/codec"
+	"github.com/go-meson/meson/internal/command"
+	"github.com/go-meson/meson/transport"
+	"github.com/go-meson/meson/window"
+)
+
+// serveEvents registers the events of the names in eventIDs, and answers null to the other requests.
+// The names of the registered events are sent to the returned channel.
+func serveEvents(t *testing.T, lb *transport.Loopback, eventIDs map[string]int64) <-chan string {
+	registered := make(chan string, 16)
+	go func() {
+		for req := range lb.Requests() {
+			var cmd command.Command
+			if err := json.Unmarshal(req, &cmd); err != nil {
+				t.Errorf("invalid request: %s", req)
+				continue
+			}
+			if cmd.ActionID == 0 {
+				continue
+			}
+			result, name := `null`, ""
+			if cmd.Method == "_regevent" {
+				var opt struct {
+					Name string `json:"eventName"`
+				}
+				b, _ := json.Marshal(cmd.Args)
+				json.Unmarshal(b, &opt)
+				b, _ = json.Marshal(eventIDs[opt.Name])
+				result, name = string(b), opt.Name
+			}
+			resp := command.Response{Action: binding.ActReply, ActionID: cmd.ActionID, Type: cmd.Type, ID: cmd.ID, Result: codec.Raw(result)}
+			b, _ := json.Marshal(&resp)
+			if _, err := lb.Post(0, b, false); err != nil {
+				t.Error(err)
+			}
+			if name != "" {
+				select {
+				case registered <- name:
+				default:
+				}
+			}
+		}
+	}()
+	return registered
+}
+
+func emitAppEvent(t *testing.T, lb *transport.Loopback, eventID int64, payload string) string {
+	evt := command.Response{Action: binding.ActEvent, Type: binding.ObjApp, ID: binding.ObjStaticID, EventID: eventID, Result: codec.Raw(payload)}
+	b, _ := json.Marshal(&evt)
+	r, err := lb.Post(0, b, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(r)
+}
+
+func TestAppEvents(t *testing.T) {
+	lb := transport.NewLoopback()
+	command.SetTransport(lb)
+	registered := serveEvents(t, lb, map[string]int64{"open-file": 1, "browser-window-created": 2, "closed": 3})
+	lb.SetReady()
+	<-command.Ready()
+
+	var opened string
+	s, err := OnOpenFile(func(path string) bool {
+		opened = path
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Unsubscribe()
+	if r := emitAppEvent(t, lb, 1, `{"path":"/doc/a.txt"}`); r != "true" || opened != "/doc/a.txt" {
+		t.Errorf("open-file: reply %s, path %q", r, opened)
+	}
+
+	var created *window.Window
+	s, err = OnBrowserWindowCreated(func(w *window.Window) {
+		created = w
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Unsubscribe()
+	emitAppEvent(t, lb, 2, `{"window":{"type":2,"id":30}}`)
+	if created == nil || created.Id != 30 || window.FromID(30) != created {
+		t.Fatalf("created window is not resolved: %#v", created)
+	}
+	defer created.Destroyed()
+	// the window watches 'closed' in the background; wait it not to leak into the next test.
+	for name := range registered {
+		if name == "closed" {
+			break
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-meson/meson/apierror"
+	"github.com/go-meson/meson/internal/binding"
+	"github.com/go-meson/meson/internal/command"
+	"github.com/go-meson/meson/internal/paths"
+	"github.com/go-meson/meson/util"
+)
+
+// Names of the paths for GetPath and SetPath.
+const (
+	// PathUserData is the directory for the configuration and the data of the application.
+	// The browser profile goes there when it is set before the framework starts.
+	PathUserData = paths.UserData
+	// PathCache is the directory for the cache files of the application.
+	PathCache = "cache"
+	// PathLogs is the directory for the log files of the application.
+	PathLogs = "logs"
+	// PathTemp is the directory for temporary files.
+	PathTemp = "temp"
+	// PathDownloads is the user's downloads directory.
+	PathDownloads = "downloads"
+	// PathDocuments is the user's documents directory.
+	PathDocuments = "documents"
+	// PathDesktop is the user's desktop directory.
+	PathDesktop = "desktop"
+	// PathHome is the user's home directory.
+	PathHome = "home"
+	// PathExe is the executable file of the application.
+	PathExe = "exe"
+)
+
+var (
+	pathLock sync.Mutex // serializes SetPath and applyPaths
+	// appliedReady is the ready channel of the lifecycle for which applyPaths has run.
+	appliedReady <-chan struct{}
+)
+
+// GetPath returns the path of name, which is one of the Path constants.
+//
+// The paths of the application, such as PathUserData, are the ones of the system
+// joined with util.ApplicationName, unless they are overridden by SetPath.
+func GetPath(name string) (string, error) {
+	if p, ok := paths.Override(name); ok {
+		return p, nil
+	}
+	p, ok := defaultPath(name)
+	if !ok {
+		return "", pathError(apierror.CodeInvalidArgument, "getPath", fmt.Sprintf("unknown path name %q", name))
+	}
+	if p == "" {
+		return "", pathError(apierror.CodeUnsupported, "getPath", fmt.Sprintf("path %q is not available", name))
+	}
+	return p, nil
+}
+
+// defaultPath returns the path of name unless it is overridden. ok is false if name is unknown.
+func defaultPath(name string) (p string, ok bool) {
+	switch name {
+	case PathUserData:
+		return appDirectory(util.ConfigDirectory), true
+	case PathCache:
+		return appDirectory(util.UserCacheDirectory), true
+	case PathLogs:
+		return appDirectory(util.LogsDirectory), true
+	case PathTemp:
+		return util.GetSystemDirectoryPath(util.TempDirectory), true
+	case PathDownloads:
+		return util.GetSystemDirectoryPath(util.DownloadsDirectory), true
+	case PathDocuments:
+		return util.GetSystemDirectoryPath(util.DocumentDirectory), true
+	case PathDesktop:
+		return util.GetSystemDirectoryPath(util.DesktopDirectory), true
+	case PathHome:
+		p, _ = os.UserHomeDir()
+		return p, true
+	case PathExe:
+		p, _ = os.Executable()
+		return p, true
+	}
+	return "", false
+}
+
+// SetPath overrides the path of name with the absolute path p.
+//
+// The framework is told about it too. PathUserData must be set before meson.Run
+// to put the browser profile there. It fails once the application has exited.
+func SetPath(name string, p string) error {
+	if _, ok := defaultPath(name); !ok {
+		return pathError(apierror.CodeInvalidArgument, "setPath", fmt.Sprintf("unknown path name %q", name))
+	}
+	if !filepath.IsAbs(p) {
+		return pathError(apierror.CodeInvalidArgument, "setPath", fmt.Sprintf("path %q is not absolute", p))
+	}
+	p = filepath.Clean(p)
+	pathLock.Lock()
+	defer pathLock.Unlock()
+	state := command.CurrentState()
+	if state == command.StateExited {
+		return pathError(apierror.CodeNotReady, "setPath", "application has exited")
+	}
+	paths.Set(name, p)
+	if state == command.StateStarting && appliedReady != command.Ready() {
+		// applied by applyPaths once ready.
+		return nil
+	}
+	return command.Post(&app.Object, "setPath", name, p)
+}
+
+// applyPaths tells the framework about the paths set before it is ready.
+// The paths set after that are sent by SetPath, even if the state is still starting.
+func applyPaths() error {
+	pathLock.Lock()
+	defer pathLock.Unlock()
+	appliedReady = command.Ready()
+	for name, p := range paths.Overrides() {
+		if err := command.Post(&app.Object, "setPath", name, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appDirectory(dir util.SystemDirectoryType) string {
+	p := util.GetSystemDirectoryPath(dir)
+	if p == "" {
+		return ""
+	}
+	return filepath.Join(p, util.ApplicationName)
+}
+
+func pathError(code apierror.Code, method string, message string) error {
+	return apierror.New(code, binding.ObjApp, binding.ObjStaticID, method, message)
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-meson/meson/apierror"
+	"github.com/go-meson/meson/internal/command"
+	"github.com/go-meson/meson/internal/paths"
+	"github.com/go-meson/meson/transport"
+	"github.com/go-meson/meson/util"
+)
+
+func TestPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/conf")
+	if prev, ok := paths.Override(PathLogs); ok {
+		defer paths.Set(PathLogs, prev)
+	} else {
+		defer paths.Delete(PathLogs)
+	}
+	// a new lifecycle, which is starting.
+	command.SetTransport(transport.NewLoopback())
+
+	if p, err := GetPath(PathUserData); err != nil || p != filepath.Join("/conf", util.ApplicationName) {
+		t.Errorf("userData = %q, %v", p, err)
+	}
+	if _, err := GetPath("nowhere"); apierror.CodeOf(err) != apierror.CodeInvalidArgument {
+		t.Errorf("unknown name must be invalid: %v", err)
+	}
+	if err := SetPath(PathLogs, "relative/logs"); apierror.CodeOf(err) != apierror.CodeInvalidArgument {
+		t.Errorf("relative path must be invalid: %v", err)
+	}
+	if err := SetPath(PathLogs, "/var/log/app/"); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := GetPath(PathLogs); err != nil || p != "/var/log/app" {
+		t.Errorf("logs = %q, %v", p, err)
+	}
+	if p, _ := paths.Override(PathLogs); p != "/var/log/app" {
+		t.Errorf("override = %q", p)
+	}
+	command.SetState(command.StateExited)
+	if err := SetPath(PathLogs, "/tmp/logs"); apierror.CodeOf(err) != apierror.CodeNotReady {
+		t.Errorf("path is set after exit: %v", err)
+	}
+	if p, _ := GetPath(PathLogs); p != "/var/log/app" {
+		t.Errorf("logs = %q after exit", p)
+	}
+}
+
+func TestSetPathAfterApply(t *testing.T) {
+	if prev, ok := paths.Override(PathCache); ok {
+		defer paths.Set(PathCache, prev)
+	} else {
+		defer paths.Delete(PathCache)
+	}
+	lb := transport.NewLoopback()
+	command.SetTransport(lb)
+	sent := make(chan []interface{}, 16)
+	go func() {
+		for req := range lb.Requests() {
+			var cmd command.Command
+			if json.Unmarshal(req, &cmd) == nil && cmd.Method == "setPath" {
+				args, _ := cmd.Args.([]interface{})
+				sent <- args
+			}
+		}
+	}()
+
+	// the paths are applied in the ready hook, and the state is still starting.
+	if err := applyPaths(); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetPath(PathCache, "/var/cache/app"); err != nil {
+		t.Fatal(err)
+	}
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case args := <-sent:
+			if len(args) == 2 && args[0] == PathCache && args[1] == "/var/cache/app" {
+				return
+			}
+		case <-timeout:
+			t.Fatal("path set after applyPaths is not sent")
+		}
+	}
+}
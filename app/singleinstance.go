@@ -0,0 +1,242 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-meson/meson/event"
+	"github.com/go-meson/meson/internal/command"
+	"github.com/go-meson/meson/internal/object"
+	"github.com/go-meson/meson/util"
+)
+
+// secondInstanceDialTimeout is how long a second instance waits for the first one to listen.
+const secondInstanceDialTimeout = 3 * time.Second
+
+// maxSocketPath is the longest path of a unix socket, which fits sun_path on every platform.
+const maxSocketPath = 103
+
+// instanceMessage is sent from a second instance to the first one.
+type instanceMessage struct {
+	Args []string `json:"args"`
+	Cwd  string   `json:"cwd"`
+}
+
+// instanceLock is the lock of the first instance, and the socket which receives the second instances.
+type instanceLock struct {
+	file     *os.File
+	listener net.Listener
+	sockPath string
+	done     chan struct{}
+}
+
+var (
+	instanceMutex sync.Mutex
+	instance      *instanceLock
+
+	secondInstanceMutex    sync.Mutex
+	secondInstanceNextID   int64
+	secondInstanceHandlers = make(map[int64]func(args []string, cwd string))
+)
+
+// RequestSingleInstanceLock makes this process the single instance of the application named util.ApplicationName.
+//
+// It returns true if this process is the first instance. Otherwise the command line arguments and
+// the working directory of this process are handed to the first instance, which emits 'second-instance',
+// and it returns false; the caller should exit then.
+// The lock is released when the application has exited, or by ReleaseSingleInstanceLock.
+func RequestSingleInstanceLock() (bool, error) {
+	instanceMutex.Lock()
+	defer instanceMutex.Unlock()
+	if instance != nil {
+		return true, nil
+	}
+	base := instanceBasePath()
+	l, err := acquireInstanceLock(base)
+	if err != nil {
+		return false, err
+	}
+	if l == nil {
+		cwd, _ := os.Getwd()
+		return false, sendToInstance(instanceSocketPath(base), instanceMessage{Args: os.Args, Cwd: cwd})
+	}
+	instance = l
+	go l.serve(emitSecondInstance)
+	go func() {
+		if command.WaitState(context.Background(), command.StateExited) == nil {
+			ReleaseSingleInstanceLock()
+		}
+	}()
+	return true, nil
+}
+
+// ReleaseSingleInstanceLock releases the lock taken by RequestSingleInstanceLock.
+// Another instance can take the lock after that.
+func ReleaseSingleInstanceLock() {
+	instanceMutex.Lock()
+	defer instanceMutex.Unlock()
+	if instance != nil {
+		instance.close()
+		instance = nil
+	}
+}
+
+// OnSecondInstance set 'second-instance' event handler.
+//
+// 'second-instance' emitted when another instance of the application is launched,
+// with its command line arguments and working directory. It requires RequestSingleInstanceLock.
+func OnSecondInstance(callback func(args []string, cwd string)) (*event.Subscription, error) {
+	secondInstanceMutex.Lock()
+	defer secondInstanceMutex.Unlock()
+	secondInstanceNextID++
+	id := secondInstanceNextID
+	secondInstanceHandlers[id] = callback
+	return object.NewSubscription(func() {
+		secondInstanceMutex.Lock()
+		defer secondInstanceMutex.Unlock()
+		delete(secondInstanceHandlers, id)
+	}), nil
+}
+
+func emitSecondInstance(m instanceMessage) {
+	secondInstanceMutex.Lock()
+	handlers := make([]func([]string, string), 0, len(secondInstanceHandlers))
+	for id := int64(1); id <= secondInstanceNextID; id++ {
+		if h, ok := secondInstanceHandlers[id]; ok {
+			handlers = append(handlers, h)
+		}
+	}
+	secondInstanceMutex.Unlock()
+	for _, h := range handlers {
+		callSecondInstanceHandler(h, m)
+	}
+}
+
+func callSecondInstanceHandler(h func([]string, string), m instanceMessage) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("second-instance handler panic: %v", r)
+		}
+	}()
+	h(m.Args, m.Cwd)
+}
+
+// instanceBasePath returns the path of the lock file and the socket without extension.
+// It is in the user runtime directory, or the user cache directory if there is none.
+func instanceBasePath() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" || !filepath.IsAbs(dir) {
+		dir = util.GetSystemDirectoryPath(util.UserCacheDirectory)
+	}
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == 0 {
+			return '_'
+		}
+		return r
+	}, util.ApplicationName)
+	return filepath.Join(dir, name+"-instance")
+}
+
+// instanceSocketPath returns the path of the socket of base.
+// A path too long for a unix socket is replaced by a hashed name in the temporary directory.
+func instanceSocketPath(base string) string {
+	p := base + ".sock"
+	if len(p) <= maxSocketPath {
+		return p
+	}
+	sum := sha256.Sum256([]byte(base))
+	return filepath.Join(os.TempDir(), fmt.Sprintf("meson-%x.sock", sum[:8]))
+}
+
+// acquireInstanceLock takes the lock of base.
+// It returns nil without error if another process holds the lock.
+func acquireInstanceLock(base string) (*instanceLock, error) {
+	if err := os.MkdirAll(filepath.Dir(base), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(base+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, nil
+		}
+		return nil, err
+	}
+	sockPath := instanceSocketPath(base)
+	// the socket is left by a crashed instance, as we hold the lock.
+	os.Remove(sockPath)
+	ln, err := net.Listen("unix", sockPath)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &instanceLock{file: f, listener: ln, sockPath: sockPath, done: make(chan struct{})}, nil
+}
+
+func (l *instanceLock) serve(handler func(instanceMessage)) {
+	defer close(l.done)
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(secondInstanceDialTimeout))
+		var m instanceMessage
+		if err := json.NewDecoder(conn).Decode(&m); err != nil {
+			log.Printf("invalid second instance message: %v", err)
+			conn.Close()
+			continue
+		}
+		conn.Write([]byte("{}\n"))
+		conn.Close()
+		// the handler may release the lock, which waits this loop to end.
+		go handler(m)
+	}
+}
+
+func (l *instanceLock) close() {
+	l.listener.Close()
+	<-l.done
+	os.Remove(l.sockPath)
+	l.file.Close()
+}
+
+// sendToInstance hands m to the first instance listening on sockPath, and waits until it is received.
+func sendToInstance(sockPath string, m instanceMessage) error {
+	var conn net.Conn
+	var err error
+	deadline := time.Now().Add(secondInstanceDialTimeout)
+	for {
+		// the first instance may not listen yet.
+		conn, err = net.Dial("unix", sockPath)
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err != nil {
+		return fmt.Errorf("connect to the first instance fail: %v", err)
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(&m); err != nil {
+		return err
+	}
+	var ack struct{}
+	return json.NewDecoder(conn).Decode(&ack)
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInstanceLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meson-instance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	base := filepath.Join(dir, "app-instance")
+
+	first, err := acquireInstanceLock(base)
+	if err != nil || first == nil {
+		t.Fatalf("first instance: %v, %v", first, err)
+	}
+	received := make(chan instanceMessage, 1)
+	go first.serve(func(m instanceMessage) {
+		received <- m
+	})
+
+	second, err := acquireInstanceLock(base)
+	if err != nil || second != nil {
+		t.Fatalf("second instance must not get the lock: %v, %v", second, err)
+	}
+	sent := instanceMessage{Args: []string{"app", "file.txt"}, Cwd: "/work"}
+	if err := sendToInstance(instanceSocketPath(base), sent); err != nil {
+		t.Fatal(err)
+	}
+	if m := <-received; !reflect.DeepEqual(m, sent) {
+		t.Errorf("received %#v, want %#v", m, sent)
+	}
+
+	first.close()
+	third, err := acquireInstanceLock(base)
+	if err != nil || third == nil {
+		t.Fatalf("lock must be taken after release: %v, %v", third, err)
+	}
+	// a handler can release the lock, as ReleaseSingleInstanceLock does.
+	released := make(chan struct{})
+	go third.serve(func(instanceMessage) {
+		third.close()
+		close(released)
+	})
+	if err := sendToInstance(instanceSocketPath(base), sent); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-released:
+	case <-time.After(3 * time.Second):
+		t.Fatal("releasing the lock from the handler deadlocks")
+	}
+}
+
+func TestInstanceSocketPath(t *testing.T) {
+	base := filepath.Join("/tmp", strings.Repeat("long-directory/", 10), "app-instance")
+	p := instanceSocketPath(base)
+	if len(p) > maxSocketPath {
+		t.Errorf("socket path is too long: %s", p)
+	}
+	if p != instanceSocketPath(base) {
+		t.Error("socket path is not stable")
+	}
+	if short := "/tmp/app-instance"; instanceSocketPath(short) != short+".sock" {
+		t.Errorf("short path is changed: %s", instanceSocketPath(short))
+	}
+}
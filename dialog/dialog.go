@@ -1,15 +1,15 @@
 package dialog
 
 import (
-	"encoding/json"
-	"errors"
+	"context"
+	"github.com/go-meson/meson/apierror"
 	"github.com/go-meson/meson/internal/binding"
+	"github.com/go-meson/meson/internal/codec"
 	"github.com/go-meson/meson/internal/command"
 	"github.com/go-meson/meson/internal/event"
 	obj "github.com/go-meson/meson/internal/object"
 	"github.com/go-meson/meson/object"
 	"github.com/go-meson/meson/window"
-	"log"
 )
 
 // MessageBoxOpt is dialog box creation options.
@@ -64,6 +64,11 @@ func makeMsgBoxOpt(message string, title string, messageBoxType MessageBoxType,
 }
 
 func ShowMessageBox(window *window.Window, message string, title string, messageBoxType MessageBoxType, opt *MessageBoxOpt) (int, error) {
+	return ShowMessageBoxContext(context.Background(), window, message, title, messageBoxType, opt)
+}
+
+// ShowMessageBoxContext is same as ShowMessageBox, but gives up waiting the user's choice when ctx is done.
+func ShowMessageBoxContext(ctx context.Context, window *window.Window, message string, title string, messageBoxType MessageBoxType, opt *MessageBoxOpt) (int, error) {
 	tmpl := makeMsgBoxOpt(message, title, messageBoxType, opt)
 	var winid int64
 	if window != nil {
@@ -71,12 +76,12 @@ func ShowMessageBox(window *window.Window, message string, title string, message
 	}
 	//TODO: dialog static method
 	cmd := command.MakeCallCommand(binding.ObjDialog, binding.ObjStaticID, "showMessageBox", winid, &tmpl)
-	r, err := command.SendMessage(&cmd)
+	r, err := command.SendMessageContext(ctx, &cmd)
 	if err != nil {
 		return -1, err
 	}
 	var buttonID int
-	err = json.Unmarshal(r, &buttonID)
+	err = codec.Decode(r, &buttonID)
 	if err != nil {
 		return -1, err
 	}
@@ -86,30 +91,27 @@ func ShowMessageBox(window *window.Window, message string, title string, message
 type msgBoxCallbackHandler func(int, error)
 
 type msgBoxCallbackItem struct {
-	f       msgBoxCallbackHandler
-	eventID int64
-	eventNo int
+	f   msgBoxCallbackHandler
+	sub *obj.Subscription
 }
 
-func (mb msgBoxCallbackItem) Call(o object.ObjectRef, arg json.RawMessage) (bool, error) {
+func (mb *msgBoxCallbackItem) Call(o object.ObjectRef, arg codec.Raw) (bool, error) {
 	args := struct {
 		ButtonID int `json:"buttonID"`
 	}{}
-	err := json.Unmarshal(arg, &args)
+	err := codec.Decode(arg, &args)
 	if err != nil {
 		mb.f(-1, err)
 	} else {
 		mb.f(args.ButtonID, nil)
 	}
-	obj := o.(*obj.Object)
-	log.Printf("eventID = %d, eventNo = %d\n", mb.eventID, mb.eventNo)
-	event.DeleteRegisterdCallback(obj, mb.eventID, mb.eventNo)
+	mb.sub.Unsubscribe()
 	return false, nil
 }
 
 func ShowMessageBoxAsync(window *window.Window, message string, title string, messageBoxType MessageBoxType, opt *MessageBoxOpt, handler func(int, error)) {
 	if handler == nil {
-		panic(errors.New("invalid argument"))
+		panic(apierror.ErrInvalidArgument)
 	}
 	tmpl := makeMsgBoxOpt(message, title, messageBoxType, opt)
 	var winid int64
@@ -124,14 +126,13 @@ func ShowMessageBoxAsync(window *window.Window, message string, title string, me
 		}
 		eventID := items[0].EventID
 		eventName := items[0].EventName
-		item := &msgBoxCallbackItem{f: handler, eventID: eventID}
-		eventNo := dlgCls.AddRegisterdCallback(eventID, item)
-		item.eventNo = eventNo
+		item := &msgBoxCallbackItem{f: handler}
+		item.sub = event.AddTemporaryCallback(dlgCls, eventID, item)
 		cmd := command.MakeCallCommand(dlgCls.ObjType, dlgCls.Id, "showMessageBox", winid, &tmpl, eventName)
 		if err := command.SendMessageAsync(&cmd, func(r *command.Response) {
 			if err := command.CheckResponse(r); err != nil {
 				handler(-1, err)
-				event.DeleteRegisterdCallback(dlgCls, eventID, eventNo)
+				item.sub.Unsubscribe()
 				return
 			}
 		}); err != nil {
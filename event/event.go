@@ -1,9 +1,126 @@
+// Package event subscribes to the events emitted by the meson framework.
 package event
 
 import (
+	"fmt"
+	"reflect"
+
+	"github.com/go-meson/meson/apierror"
+	"github.com/go-meson/meson/internal/codec"
+	"github.com/go-meson/meson/internal/command"
+	"github.com/go-meson/meson/internal/event"
+	internal "github.com/go-meson/meson/internal/object"
 	"github.com/go-meson/meson/object"
 )
 
 type CommonCallbackHandler func(object.ObjectRef)
 
 type CommonPreventableCallbackHandler func(object.ObjectRef) bool
+
+// Subscription is a registered event handler, returned by On... functions.
+// Call Unsubscribe to remove the handler.
+type Subscription = internal.Subscription
+
+// Payload is the undecoded payload of an event. A handler taking Payload decodes it by itself.
+type Payload struct {
+	raw codec.Raw
+}
+
+// Decode decodes the payload into v.
+func (p Payload) Decode(v interface{}) error {
+	if len(p.raw) == 0 {
+		return nil
+	}
+	return codec.Decode(p.raw, v)
+}
+
+var (
+	refType     = reflect.TypeOf((*object.ObjectRef)(nil)).Elem()
+	payloadType = reflect.TypeOf(Payload{})
+)
+
+// Subscribe adds handler for the framework event called name of target, such as a *window.Window.
+//
+// handler is a func in one of the following forms, where T is any type the payload of the event decodes into:
+//
+//	func(sender object.ObjectRef)
+//	func(sender object.ObjectRef, payload T)
+//	func(sender object.ObjectRef) bool
+//	func(sender object.ObjectRef, payload T) bool
+//
+// A handler returning true prevents the default action of a preventable event.
+// When the payload can not be decoded into T, the handler is not called,
+// and the error is reported to the fault handler of the meson package.
+func Subscribe(target object.ObjectRef, name string, handler interface{}) (*Subscription, error) {
+	base, ok := target.(interface {
+		Base() *internal.Object
+	})
+	if !ok {
+		return nil, apierror.New(apierror.CodeInvalidArgument, target.GetObjectType(), target.GetID(), name, fmt.Sprintf("%T is not a meson object", target))
+	}
+	item, err := newHandlerItem(name, handler)
+	if err != nil {
+		return nil, apierror.New(apierror.CodeInvalidArgument, target.GetObjectType(), target.GetID(), name, err.Error())
+	}
+	return event.AddCallback(base.Base(), name, item)
+}
+
+// handlerItem calls a handler of Subscribe with the decoded payload.
+type handlerItem struct {
+	name        string
+	f           reflect.Value
+	payload     reflect.Type // nil if the handler takes no payload
+	preventable bool
+}
+
+func newHandlerItem(name string, handler interface{}) (*handlerItem, error) {
+	f := reflect.ValueOf(handler)
+	t := f.Type()
+	if t.Kind() != reflect.Func || f.IsNil() {
+		return nil, fmt.Errorf("handler is not a func: %T", handler)
+	}
+	if t.NumIn() < 1 || t.NumIn() > 2 || t.In(0) != refType {
+		return nil, fmt.Errorf("handler must take object.ObjectRef and an optional payload: %s", t)
+	}
+	if t.NumOut() > 1 || (t.NumOut() == 1 && t.Out(0).Kind() != reflect.Bool) {
+		return nil, fmt.Errorf("handler must return nothing or bool: %s", t)
+	}
+	item := &handlerItem{name: name, f: f, preventable: t.NumOut() == 1}
+	if t.NumIn() == 2 {
+		item.payload = t.In(1)
+	}
+	return item, nil
+}
+
+func (h *handlerItem) Call(o object.ObjectRef, arg codec.Raw) (bool, error) {
+	args := []reflect.Value{reflect.ValueOf(&o).Elem()}
+	if h.payload != nil {
+		v, err := h.decode(arg)
+		if err != nil {
+			command.ReportFault(&command.Fault{
+				Kind:    command.FaultEventPayload,
+				ObjType: o.GetObjectType(),
+				ID:      o.GetID(),
+				Message: arg,
+				Err:     fmt.Errorf("event %q: %v", h.name, err),
+			})
+			return false, nil
+		}
+		args = append(args, v)
+	}
+	out := h.f.Call(args)
+	return h.preventable && out[0].Bool(), nil
+}
+
+func (h *handlerItem) decode(arg codec.Raw) (reflect.Value, error) {
+	if h.payload == payloadType {
+		return reflect.ValueOf(Payload{raw: arg}), nil
+	}
+	v := reflect.New(h.payload)
+	if len(arg) > 0 {
+		if err := codec.Decode(arg, v.Interface()); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	return v.Elem(), nil
+}
@@ -0,0 +1,81 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/go-meson/meson/apierror"
+	"github.com/go-meson/meson/internal/binding"
+	"github.com/go-meson/meson/internal/codec"
+	"github.com/go-meson/meson/internal/command"
+	internal "github.com/go-meson/meson/internal/object"
+	"github.com/go-meson/meson/object"
+	"github.com/go-meson/meson/transport"
+)
+
+func TestSubscribePayload(t *testing.T) {
+	lb := transport.NewLoopback()
+	command.SetTransport(lb)
+	go func() {
+		for req := range lb.Requests() {
+			var cmd command.Command
+			if err := json.Unmarshal(req, &cmd); err != nil || cmd.ActionID == 0 {
+				continue
+			}
+			result := `null`
+			if cmd.Method == "_regevent" {
+				result = `7`
+			}
+			resp := command.Response{Action: binding.ActReply, ActionID: cmd.ActionID, Result: codec.Raw(result)}
+			b, _ := json.Marshal(&resp)
+			lb.Post(0, b, false)
+		}
+	}()
+	lb.SetReady()
+	<-command.Ready()
+
+	o := internal.NewObject(50, binding.ObjWindow)
+	internal.AddObject(binding.ObjWindow, o.Id, &o)
+	defer o.Destroyed()
+
+	type moved struct {
+		X int `json:"x"`
+	}
+	var got moved
+	if _, err := Subscribe(&o, "move", func(sender object.ObjectRef, p moved) bool {
+		got = p
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Subscribe(&o, "move", func(sender object.ObjectRef, p int) {}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Subscribe(&o, "move", func(p moved) {}); !errors.Is(err, apierror.ErrInvalidArgument) {
+		t.Errorf("invalid handler is accepted: %v", err)
+	}
+
+	faults := make(chan *command.Fault, 1)
+	command.SetFaultHandler(func(f *command.Fault) command.FaultAction {
+		faults <- f
+		return command.FaultDrop
+	})
+	defer command.SetFaultHandler(nil)
+
+	evt := command.Response{Action: binding.ActEvent, Type: binding.ObjWindow, ID: o.Id, EventID: 7, Result: codec.Raw(`{"x":3}`)}
+	b, _ := json.Marshal(&evt)
+	result, err := lb.Post(0, b, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != "true" {
+		t.Errorf("event is not prevented: %s", result)
+	}
+	if got.X != 3 {
+		t.Errorf("unexpected payload: %#v", got)
+	}
+	if f := <-faults; f.Kind != command.FaultEventPayload {
+		t.Errorf("unexpected fault: %v", f)
+	}
+}
@@ -13,7 +13,6 @@ import (
 	"github.com/go-meson/meson/util"
 	"github.com/go-meson/meson/window"
 	"net/url"
-	"os/user"
 	"path/filepath"
 )
 
@@ -58,12 +57,16 @@ type winUserData struct {
 }
 
 func setupLogger() {
-	u, err := user.Current()
+	dir, err := app.GetPath(app.PathLogs)
 	if err != nil {
 		log.Fatal(err)
 		return
 	}
-	if err := logger.SetFileLogger(filepath.Join(u.HomeDir, util.ApplicationName+".log")); err != nil {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Fatal(err)
+		return
+	}
+	if err := logger.SetFileLogger(filepath.Join(dir, util.ApplicationName+".log")); err != nil {
 		log.Fatal(err)
 		return
 	}
@@ -105,6 +108,8 @@ func main() {
 			if ud.doClosing {
 				return false
 			}
+			// the framework waits for the reply of 'close', so a synchronous call can not be made here
+			// unless the framework serves nested requests by MesonApiDispatchRequest.
 			dialog.ShowMessageBoxAsync(win, "really quit?", "Quit?", dialog.MessageBoxTypeQuestion, nil, func(buttonId int, err error) {
 				if err != nil {
 					log.Panicf("err!: %#v", err)
@@ -9,14 +9,26 @@ enum {
   API_MesonApiAddArgv,
   API_MesonApiMain,
   API_MesonApiSetHandler,
+  API_MesonApiDispatchRequest,
+  API_MesonApiSetBinaryHandler,
+  API_MesonApiDispatchBinaryRequest,
+  API_MesonApiAbort,
 };
 
 MesonExportFunction MesonFrameworkFunctions[] = {
-    {"MesonApiSetArgc", NULL},
-    {"MesonApiAddArgv", NULL},
-    {"MesonApiMain", NULL},
-    {"MesonApiSetHandler", NULL},
-    {NULL, NULL},
+    {"MesonApiSetArgc", NULL, 0},
+    {"MesonApiAddArgv", NULL, 0},
+    {"MesonApiMain", NULL, 0},
+    {"MesonApiSetHandler", NULL, 0},
+    // optional: processes a request on the calling thread, and delivers its reply before return.
+    {"MesonApiDispatchRequest", NULL, 1},
+    // optional: MesonApiSetHandler with length-delimited messages instead of NUL-terminated strings.
+    {"MesonApiSetBinaryHandler", NULL, 1},
+    // optional: MesonApiDispatchRequest with a length-delimited request.
+    {"MesonApiDispatchBinaryRequest", NULL, 1},
+    // optional: stops the main loop with an exit code from any thread, even before the framework is ready.
+    {"MesonApiAbort", NULL, 1},
+    {NULL, NULL, 0},
 };
 
 void MesonApiSetArgc(int argc) {
@@ -40,6 +52,41 @@ int MesonApiMain(void) {
   return pfn();
 }
 
+int mesonHasBinaryHandler() {
+  return MesonFrameworkFunctions[API_MesonApiSetBinaryHandler].func != NULL;
+}
+
+int mesonCanDispatchRequest() {
+  if (mesonHasBinaryHandler()) {
+    return MesonFrameworkFunctions[API_MesonApiDispatchBinaryRequest].func != NULL;
+  }
+  return MesonFrameworkFunctions[API_MesonApiDispatchRequest].func != NULL;
+}
+
+void mesonDispatchRequest(const char *request) {
+  typedef void (*tfn)(const char *request);
+  tfn pfn = (tfn)MesonFrameworkFunctions[API_MesonApiDispatchRequest].func;
+  assert(pfn);
+  pfn(request);
+}
+
+void mesonDispatchBinaryRequest(const void *request, unsigned int len) {
+  typedef void (*tfn)(const void *request, unsigned int len);
+  tfn pfn = (tfn)MesonFrameworkFunctions[API_MesonApiDispatchBinaryRequest].func;
+  assert(pfn);
+  pfn(request, len);
+}
+
+int mesonAbort(int code) {
+  typedef void (*tfn)(int code);
+  tfn pfn = (tfn)MesonFrameworkFunctions[API_MesonApiAbort].func;
+  if (!pfn) {
+    return 0;
+  }
+  pfn(code);
+  return 1;
+}
+
 static void mesonCallInitHandler(void)
 {
 	goCallInit();
@@ -53,8 +100,23 @@ static char* mesonPostServerResponseHandler(unsigned int id, const char* respons
   return goPostServerResponse(id, (char* )response, needReply);
 
 }
+static void* mesonWaitServerBinaryRequestHandler(unsigned int* pLen)
+{
+  return goWaitServerBinaryRequest(pLen);
+}
+static void* mesonPostServerBinaryResponseHandler(unsigned int id, const void* response, unsigned int len, int needReply, unsigned int* pReplyLen)
+{
+  return goPostServerBinaryResponse(id, (void* )response, len, needReply, pReplyLen);
+}
 void mesonRegistHandler()
 {
+  if (mesonHasBinaryHandler()) {
+    typedef void (*tfn)(MesonInitHandler, MesonWaitServerBinaryRequestHandler,
+                        MesonPostServerBinaryResponseHandler);
+    tfn pfn = (tfn)MesonFrameworkFunctions[API_MesonApiSetBinaryHandler].func;
+    pfn(mesonCallInitHandler, mesonWaitServerBinaryRequestHandler, mesonPostServerBinaryResponseHandler);
+    return;
+  }
   typedef void (*tfn)(MesonInitHandler, MesonWaitServerRequestHandler,
                       MesonPostServerResponseHandler);
   tfn pfn = (tfn)MesonFrameworkFunctions[API_MesonApiSetHandler].func;
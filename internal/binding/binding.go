@@ -6,6 +6,7 @@ package binding
 #cgo framework_debug	CFLAGS: -I ../../../framework/src/api
 #cgo darwin LDFLAGS: -mmacosx-version-min=10.9
 #cgo darwin LDFLAGS: -framework Foundation
+#cgo linux LDFLAGS: -ldl
 
 #include <stdlib.h>
 #include "binding.h"
@@ -14,9 +15,9 @@ package binding
 import "C"
 import "unsafe"
 import "errors"
-import "runtime"
-import "sync/atomic"
+import "sync"
 import "github.com/go-meson/meson/object"
+import "github.com/go-meson/meson/transport"
 import "fmt"
 import "reflect"
 
@@ -65,33 +66,19 @@ const (
 	MessageBoxTypeQuestion                = C.MESON_DIALOG_MESSAGEBOX_TYPE_QUESTION
 )
 
-//MessageReceiveHandler is message receive handler.
-type MessageReceiveHandler func(int64, string, bool) string
-
 var (
-	sendLock       int32
 	ReadyChannel   = make(chan struct{})
+	readyOnce      sync.Once
 	requestChannel = make(chan []byte)
-	receiveHandler MessageReceiveHandler
+	receiveHandler transport.ReceiveHandler
+	pumpLock       = sync.Mutex{}
+	pumps          []*messagePump
 )
 
-func LockSendMessage() {
-	for {
-		if atomic.CompareAndSwapInt32(&sendLock, 0, 1) {
-			return
-		}
-		runtime.Gosched()
-	}
-}
-
-func TryEnterSendMessage() bool {
-	return atomic.CompareAndSwapInt32(&sendLock, 0, 1)
-}
-
-func LeaveSendMessage() {
-	if !atomic.CompareAndSwapInt32(&sendLock, 1, 0) {
-		panic("invalid unlock timing")
-	}
+// messagePump holds the requests posted while a reply to the framework is pending.
+type messagePump struct {
+	queue [][]byte
+	wake  chan struct{}
 }
 
 func MesonFrameworkVersion() string {
@@ -109,7 +96,9 @@ func MesonFrameworkVersion() string {
 
 //export goCallInit
 func goCallInit() {
-	ReadyChannel <- struct{}{}
+	readyOnce.Do(func() {
+		close(ReadyChannel)
+	})
 }
 
 //export goWaitServerRequest
@@ -120,44 +109,194 @@ func goWaitServerRequest() *C.char {
 
 //export goPostServerResponse
 func goPostServerResponse(cid C.uint, crespstr *C.char, needReply C.int) *C.char {
-	isReply := needReply > 0
-	if isReply {
-		LockSendMessage()
-		defer LeaveSendMessage()
+	result := postServerResponse(int64(cid), []byte(C.GoString(crespstr)), needReply > 0)
+	if result == nil {
+		return nil
 	}
-	id := int64(cid)
-	respstr := C.GoString(crespstr)
-	resultstr := receiveHandler(id, respstr, isReply)
-	var cresultstr *C.char
-	if isReply {
-		cresultstr = C.CString(resultstr)
+	return C.CString(string(result))
+}
+
+//export goWaitServerBinaryRequest
+func goWaitServerBinaryRequest(clen *C.uint) unsafe.Pointer {
+	req := <-requestChannel
+	*clen = C.uint(len(req))
+	return C.CBytes(req)
+}
+
+//export goPostServerBinaryResponse
+func goPostServerBinaryResponse(cid C.uint, cresp unsafe.Pointer, clen C.uint, needReply C.int, creplyLen *C.uint) unsafe.Pointer {
+	result := postServerResponse(int64(cid), C.GoBytes(cresp, C.int(clen)), needReply > 0)
+	*creplyLen = C.uint(len(result))
+	if result == nil {
+		return nil
 	}
-	return cresultstr
+	return C.CBytes(result)
+}
+
+// postServerResponse delivers a message of the framework, and returns the reply if it needs one.
+func postServerResponse(id int64, resp []byte, needReply bool) []byte {
+	if !needReply {
+		receiveHandler(id, resp, false)
+		return nil
+	}
+	var result []byte
+	if CanDispatchRequest() {
+		result = pumpUntilReply(id, resp)
+	} else {
+		result = receiveHandler(id, resp, true)
+	}
+	if result == nil {
+		result = []byte{}
+	}
+	return result
+}
+
+// HasBinaryHandler reports whether the framework exchanges length-delimited messages,
+// which can carry any bytes.
+func HasBinaryHandler() bool {
+	return C.mesonHasBinaryHandler() != 0
+}
+
+// CanDispatchRequest reports whether the framework can process requests while it waits a reply.
+func CanDispatchRequest() bool {
+	return C.mesonCanDispatchRequest() != 0
+}
+
+// pumpUntilReply runs the handler on another goroutine, and dispatches the requests posted
+// meanwhile on the framework thread, so that synchronous calls can be made from the handler.
+func pumpUntilReply(id int64, msg []byte) []byte {
+	p := &messagePump{wake: make(chan struct{}, 1)}
+	pumpLock.Lock()
+	pumps = append(pumps, p)
+	pumpLock.Unlock()
+
+	done := make(chan []byte, 1)
+	go func() {
+		done <- receiveHandler(id, msg, true)
+	}()
+
+	var result []byte
+	finished := false
+	for {
+		if !finished {
+			select {
+			case result = <-done:
+				finished = true
+			case <-p.wake:
+			}
+		}
+		pumpLock.Lock()
+		queue := p.queue
+		p.queue = nil
+		if finished && len(queue) == 0 {
+			// pumps are nested on the framework thread, so p is the innermost one.
+			pumps = pumps[:len(pumps)-1]
+			pumpLock.Unlock()
+			return result
+		}
+		pumpLock.Unlock()
+		for _, req := range queue {
+			dispatchRequest(req)
+		}
+	}
+}
+
+func dispatchRequest(req []byte) {
+	if HasBinaryHandler() {
+		cb := C.CBytes(req)
+		defer C.free(cb)
+		C.mesonDispatchBinaryRequest(cb, C.uint(len(req)))
+		return
+	}
+	cs := C.CString(string(req))
+	defer C.free(unsafe.Pointer(cs))
+	C.mesonDispatchRequest(cs)
 }
 
 func PostMessage(req []byte) {
+	pumpLock.Lock()
+	if n := len(pumps); n > 0 {
+		p := pumps[n-1]
+		p.queue = append(p.queue, req)
+		pumpLock.Unlock()
+		select {
+		case p.wake <- struct{}{}:
+		default:
+		}
+		return
+	}
+	pumpLock.Unlock()
 	requestChannel <- req
 }
 
-func SetMessageReceiveHandler(handler MessageReceiveHandler) {
+func SetMessageReceiveHandler(handler transport.ReceiveHandler) {
 	receiveHandler = handler
 }
 
+// Transport is the transport.Transport which talks to the meson framework through cgo.
+type Transport struct{}
+
+// Send implements transport.Transport.
+func (Transport) Send(msg []byte) error {
+	PostMessage(msg)
+	return nil
+}
+
+// SetReceiveHandler implements transport.Transport.
+func (Transport) SetReceiveHandler(handler transport.ReceiveHandler) {
+	SetMessageReceiveHandler(handler)
+}
+
+// Ready implements transport.Transport.
+func (Transport) Ready() <-chan struct{} {
+	return ReadyChannel
+}
+
+// Reentrant implements transport.Reentrant.
+func (Transport) Reentrant() bool {
+	return CanDispatchRequest()
+}
+
+// Binary implements transport.Binary.
+func (Transport) Binary() bool {
+	return HasBinaryHandler()
+}
+
+// Negotiable implements transport.Negotiable.
+// Only the framework which exports MesonApiSetBinaryHandler answers the negotiation.
+func (Transport) Negotiable() bool {
+	return HasBinaryHandler()
+}
+
 func LoadBinding() error {
 	fp, err := resolveFrameworkPath()
 	if err != nil {
 		return err
 	}
+	return LoadBindingFrom(fp)
+}
+
+// LoadBindingFrom loads the framework at path, instead of the one resolved by LoadBinding.
+func LoadBindingFrom(fp string) error {
 	cs := C.CString(fp)
 	defer C.free(unsafe.Pointer(cs))
 	cret := C.loadMesonFramework(cs)
 	if cret == C.int(0) {
+		if cerr := C.mesonLoadError(); cerr != nil {
+			return fmt.Errorf("load fail framework: %s", C.GoString(cerr))
+		}
 		return errors.New("load fail framework")
 	}
 
 	return nil
 }
 
+// AbortMainLoop stops the main loop with code, even if the framework is not ready.
+// It reports false if the framework can not do it.
+func AbortMainLoop(code int) bool {
+	return C.mesonAbort(C.int(code)) != 0
+}
+
 func RunMesonMainLoop(args []string) int {
 	defer C.freeMesonFramework()
 	C.MesonApiSetArgc(C.int(len(args)))
@@ -9,13 +9,20 @@ extern "C" {
   typedef struct MesonExportFunction {
     const char* name;
     void* func;
+    int optional;
   } MesonExportFunction;
 
   extern MesonExportFunction MesonFrameworkFunctions[];
 
   extern int loadMesonFramework(const char* path);
+  extern const char* mesonLoadError();
   extern void freeMesonFramework();
   extern void mesonRegistHandler();
+  extern int mesonCanDispatchRequest();
+  extern void mesonDispatchRequest(const char* request);
+  extern int mesonHasBinaryHandler();
+  extern void mesonDispatchBinaryRequest(const void* request, unsigned int len);
+  extern int mesonAbort(int code);
   extern const unsigned char* mesonVersions();
 
 
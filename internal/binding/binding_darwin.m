@@ -4,10 +4,12 @@
 #import <Foundation/NSString.h>
 #import <CoreFoundation/CoreFoundation.h>
 #include <stdio.h>
+#include <string.h>
 #include "binding.h"
 #include "version.h"
 
 static CFBundleRef mesonFramework = NULL;
+static char mesonLoadErrorMessage[1024];
 
 static const unsigned char mesonAPIVersions[] __attribute__ ((section ("__MESON_VERSION,__meson_version"))) = {
   MESON_VERSION_MAJOR,
@@ -19,10 +21,16 @@ const unsigned char* mesonVersions() {
   return mesonAPIVersions;
 }
 
+const char* mesonLoadError()
+{
+  return mesonLoadErrorMessage[0] ? mesonLoadErrorMessage : NULL;
+}
+
 int loadMesonFramework(const char* path)
 {
   NSString* strPath;
   BOOL success = FALSE;
+  mesonLoadErrorMessage[0] = '\0';
   strPath = [NSString stringWithUTF8String:path];
   if (strPath) {
     NSURL* fileURL = [NSURL fileURLWithPath:strPath];
@@ -30,6 +38,9 @@ int loadMesonFramework(const char* path)
       CFURLRef url = (CFURLRef )fileURL;
       if (url) {
         CFBundleRef bundle = CFBundleCreate(kCFAllocatorDefault, url);
+        if (!bundle) {
+          snprintf(mesonLoadErrorMessage, sizeof(mesonLoadErrorMessage), "can not open bundle: %s", path);
+        }
         if (bundle) {
           MesonExportFunction* pExports = MesonFrameworkFunctions;
           success = TRUE;
@@ -40,11 +51,13 @@ int loadMesonFramework(const char* path)
               break;
             }
             pExports->func =  CFBundleGetFunctionPointerForName(bundle, name);
-            if (!pExports->func) {
+            CFRelease(name);
+            if (!pExports->func && !pExports->optional) {
+              size_t len = strlen(mesonLoadErrorMessage);
+              snprintf(mesonLoadErrorMessage + len, sizeof(mesonLoadErrorMessage) - len,
+                       "%s%s", success ? "missing symbols: " : ", ", pExports->name);
               success = FALSE;
-              break;
             }
-            CFRelease(name);
             ++pExports;
           }
 
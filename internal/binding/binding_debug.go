@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 )
 
@@ -26,13 +27,14 @@ func resolveFrameworkPath() (string, error) {
 	if !stat.IsDir() {
 		return "", errors.New("framework is not build yet")
 	}
-	frameworkDir = provision.GetFrameworkPathFromRootPath(frameworkDir)
-	stat, err = os.Stat(frameworkDir)
+	frameworkPath := provision.GetFrameworkPathFromRootPath(frameworkDir)
+	stat, err = os.Stat(frameworkPath)
 	if err != nil {
 		return "", err
 	}
-	if !stat.IsDir() {
+	// Meson.framework is a directory, libmeson.so is a file.
+	if stat.IsDir() != (runtime.GOOS == "darwin") {
 		return "", errors.New("framework path is broken")
 	}
-	return frameworkDir, nil
+	return frameworkPath, nil
 }
@@ -0,0 +1,65 @@
+#include <dlfcn.h>
+#include <stdio.h>
+#include <string.h>
+#include "binding.h"
+#include "version.h"
+
+static void* mesonLibrary = NULL;
+static char mesonLoadErrorMessage[1024];
+
+static const unsigned char mesonAPIVersions[] __attribute__ ((section (".meson_version"), used)) = {
+  MESON_VERSION_MAJOR,
+  MESON_VERSION_MINOR,
+  MESON_VERSION_REVISON,
+};
+
+const unsigned char* mesonVersions() {
+  return mesonAPIVersions;
+}
+
+const char* mesonLoadError()
+{
+  return mesonLoadErrorMessage[0] ? mesonLoadErrorMessage : NULL;
+}
+
+int loadMesonFramework(const char* path)
+{
+  int success = 1;
+  mesonLoadErrorMessage[0] = '\0';
+  void* handle = dlopen(path, RTLD_NOW | RTLD_LOCAL);
+  if (!handle) {
+    const char* err = dlerror();
+    snprintf(mesonLoadErrorMessage, sizeof(mesonLoadErrorMessage), "%s", err ? err : path);
+    return 0;
+  }
+  MesonExportFunction* pExports = MesonFrameworkFunctions;
+  while (pExports->name) {
+    dlerror();
+    pExports->func = dlsym(handle, pExports->name);
+    if (!pExports->func && !pExports->optional) {
+      size_t len = strlen(mesonLoadErrorMessage);
+      snprintf(mesonLoadErrorMessage + len, sizeof(mesonLoadErrorMessage) - len,
+               "%s%s", success ? "missing symbols: " : ", ", pExports->name);
+      success = 0;
+    }
+    ++pExports;
+  }
+  if (!success) {
+    for (pExports = MesonFrameworkFunctions; pExports->name; ++pExports) {
+      pExports->func = NULL;
+    }
+    dlclose(handle);
+    return 0;
+  }
+  // keep library reference
+  mesonLibrary = handle;
+  return 1;
+}
+
+void freeMesonFramework()
+{
+  if (mesonLibrary) {
+    dlclose(mesonLibrary);
+    mesonLibrary = NULL;
+  }
+}
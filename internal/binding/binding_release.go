@@ -6,14 +6,8 @@ import (
 	"github.com/go-meson/meson/provision"
 )
 
+// resolveFrameworkPath finds the framework. GetFrameworkPath prefers a local framework,
+// such as the one next to the executable or in the bundle, and fetches it only when there is none.
 func resolveFrameworkPath() (string, error) {
-	err := provision.FetchFramework(MesonFrameworkVersion())
-	if err != nil {
-		return "", err
-	}
-	path, err := provision.GetFrameworkPath(MesonFrameworkVersion())
-	if err != nil {
-		return "", err
-	}
-	return path, nil
+	return provision.GetFrameworkPath(MesonFrameworkVersion())
 }
@@ -77,6 +77,56 @@ MESON_EXPORT void MesonApiSetHandler(MesonInitHandler pfnInitHandler,
                                      MesonWaitServerRequestHandler pfnWaitHandler,
                                      MesonPostServerResponseHandler pfnPostHandler);
 
+/*
+ * optional: stops the main loop with the exit code, so that MesonApiMain returns code.
+ * It can be called from any thread, even before pfnInitHandler is called, when the framework
+ * serves no request yet. Without it, the Go side can not stop a framework which does not get ready.
+ */
+MESON_EXPORT void MesonApiAbort(int code);
+
+/*
+ * optional: length-delimited messages and negotiation
+ *
+ * MesonApiSetBinaryHandler is called instead of MesonApiSetHandler if it is exported.
+ * Its handlers exchange length-delimited messages, which can carry any bytes.
+ * The buffers returned by the handlers are allocated by malloc, and freed by the framework.
+ *
+ * A framework exporting it answers the negotiation, the first request after pfnInitHandler:
+ * a call of the app method "_negotiate" encoded in JSON, with the argument
+ *   {"codecs": ["msgpack", "json"], "batch": true}
+ * where codecs are the acceptable codecs in order of preference, and batch tells
+ * the Go side can send batch frames. The reply is
+ *   {"codec": "msgpack", "batch": true, "cancel": true}
+ * where codec is one of codecs, used for every message after the reply, and batch tells
+ * the framework accepts a batch frame: an array of requests in one message, applied in order.
+ * cancel tells the framework accepts a call of the app method "_cancelRequest" with
+ * the argument [actionId]. It abandons the request of actionId if it is not answered yet,
+ * and replies after the reply of that request if any, as the replies are in order.
+ * A reply without a known codec keeps JSON. Other frameworks are not asked,
+ * and keep JSON without batch frames or cancels.
+ */
+typedef void* (*MesonWaitServerBinaryRequestHandler)(unsigned int* pLen);
+typedef void* (*MesonPostServerBinaryResponseHandler)(unsigned int id, const void* pMsg, unsigned int len,
+                                                      int needReply, unsigned int* pReplyLen);
+
+MESON_EXPORT void MesonApiSetBinaryHandler(MesonInitHandler pfnInitHandler,
+                                           MesonWaitServerBinaryRequestHandler pfnWaitHandler,
+                                           MesonPostServerBinaryResponseHandler pfnPostHandler);
+
+/*
+ * optional: nested requests
+ *
+ * While pfnPostHandler is called with needReply, the framework thread waits for the reply
+ * and can not take requests from pfnWaitHandler. A request made on the Go side meanwhile,
+ * such as a synchronous call from a preventable event handler, is passed to
+ * MesonApiDispatchRequest on the framework thread instead. It processes the request
+ * on the calling thread, and posts its reply by pfnPostHandler before it returns.
+ * Without it, such a call fails on the Go side and handlers must use asynchronous calls.
+ * MesonApiDispatchBinaryRequest is the same for the handlers of MesonApiSetBinaryHandler.
+ */
+MESON_EXPORT void MesonApiDispatchRequest(const char* pRequest);
+MESON_EXPORT void MesonApiDispatchBinaryRequest(const void* pRequest, unsigned int len);
+
 /*------------------------------------------------------------------------
  * internal functions
  */
@@ -0,0 +1,76 @@
+// Package codec encodes the messages exchanged with the meson framework.
+package codec
+
+import (
+	"errors"
+	"sync"
+)
+
+// Codec converts between Go values and the wire format.
+type Codec interface {
+	// Name is the name used for negotiation with the framework.
+	Name() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// Raw is an encoded value which is decoded later by Decode.
+//
+// Like json.RawMessage, it is kept as is while the enclosing message is decoded.
+type Raw []byte
+
+// MarshalJSON returns r as the JSON encoding of r.
+func (r Raw) MarshalJSON() ([]byte, error) {
+	if r == nil {
+		return []byte("null"), nil
+	}
+	return r, nil
+}
+
+// UnmarshalJSON sets *r to a copy of data.
+func (r *Raw) UnmarshalJSON(data []byte) error {
+	if r == nil {
+		return errors.New("codec.Raw: UnmarshalJSON on nil pointer")
+	}
+	*r = append((*r)[0:0], data...)
+	return nil
+}
+
+var (
+	lock          = sync.RWMutex{}
+	current Codec = JSON
+	codecs        = map[string]Codec{
+		JSON.Name():    JSON,
+		MsgPack.Name(): MsgPack,
+	}
+)
+
+// Current returns the codec in use.
+func Current() Codec {
+	lock.RLock()
+	defer lock.RUnlock()
+	return current
+}
+
+// SetCurrent changes the codec in use.
+func SetCurrent(c Codec) {
+	lock.Lock()
+	defer lock.Unlock()
+	current = c
+}
+
+// Lookup returns the codec of name.
+func Lookup(name string) (Codec, bool) {
+	c, ok := codecs[name]
+	return c, ok
+}
+
+// Marshal encodes v with the codec in use.
+func Marshal(v interface{}) ([]byte, error) {
+	return Current().Marshal(v)
+}
+
+// Decode decodes r, which is produced by the codec in use, into v.
+func Decode(r Raw, v interface{}) error {
+	return Current().Unmarshal(r, v)
+}
@@ -0,0 +1,20 @@
+package codec
+
+import "encoding/json"
+
+type jsonCodec struct{}
+
+// JSON is the default codec, which every framework understands.
+var JSON Codec = jsonCodec{}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,180 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	"github.com/vmihailenco/msgpack/v4"
+)
+
+type msgpackCodec struct{}
+
+// MsgPack is the compact binary codec based on MessagePack.
+//
+// Go values are mapped with the same rules as encoding/json: struct fields are named by their json tags.
+// Numbers decoded into interface{} are float64 as with JSON, so the result does not depend on the codec.
+// Types with a custom JSON encoding implement msgpack.Marshaler and msgpack.Unmarshaler
+// by MsgPackFromJSON and JSONFromMsgPack.
+var MsgPack Codec = msgpackCodec{}
+
+func (msgpackCodec) Name() string {
+	return "msgpack"
+}
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf).UseJSONTag(true).UseCompactEncoding(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data)).UseJSONTag(true).UseDecodeInterfaceLoose(true)
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	normalizeNumbers(reflect.ValueOf(v))
+	return nil
+}
+
+// MarshalMsgpack returns r as the MessagePack encoding of r.
+func (r Raw) MarshalMsgpack() ([]byte, error) {
+	if r == nil {
+		return []byte{0xc0}, nil // nil
+	}
+	return r, nil
+}
+
+// UnmarshalMsgpack sets *r to a copy of data.
+func (r *Raw) UnmarshalMsgpack(data []byte) error {
+	*r = append((*r)[0:0], data...)
+	return nil
+}
+
+// MsgPackFromJSON converts the JSON encoding of a value into MessagePack.
+func MsgPackFromJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return MsgPack.Marshal(fromJSONNumbers(v))
+}
+
+// JSONFromMsgPack converts the MessagePack encoding of a value into JSON.
+func JSONFromMsgPack(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := MsgPack.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// fromJSONNumbers replaces json.Number in v by int64 if it is integral, or float64.
+func fromJSONNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = fromJSONNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = fromJSONNumbers(e)
+		}
+	}
+	return v
+}
+
+var unmarshalerType = reflect.TypeOf((*msgpack.Unmarshaler)(nil)).Elem()
+
+// normalizeNumbers converts the integers decoded into interface{} values reachable from v into float64.
+func normalizeNumbers(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() && !v.Type().Implements(unmarshalerType) {
+			normalizeNumbers(v.Elem())
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		if e := v.Elem(); e.Kind() == reflect.Ptr {
+			normalizeNumbers(e)
+		} else if v.CanSet() && v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(normalizeInterface(e.Interface())))
+		}
+	case reflect.Struct:
+		if v.CanAddr() && v.Addr().Type().Implements(unmarshalerType) {
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Field(i); f.CanSet() {
+				normalizeNumbers(f)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if isScalar(v.Type().Elem()) {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			normalizeNumbers(v.Index(i))
+		}
+	case reflect.Map:
+		switch elem := v.Type().Elem(); elem.Kind() {
+		case reflect.Interface:
+			if elem.NumMethod() != 0 {
+				return
+			}
+			for _, k := range v.MapKeys() {
+				if e := v.MapIndex(k); !e.IsNil() {
+					v.SetMapIndex(k, reflect.ValueOf(normalizeInterface(e.Interface())))
+				}
+			}
+		case reflect.Ptr:
+			for _, k := range v.MapKeys() {
+				normalizeNumbers(v.MapIndex(k))
+			}
+		}
+	}
+}
+
+// normalizeInterface converts the integers in a value decoded into interface{} into float64.
+func normalizeInterface(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = normalizeInterface(e)
+		}
+	case map[interface{}]interface{}:
+		for k, e := range v {
+			v[k] = normalizeInterface(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = normalizeInterface(e)
+		}
+	}
+	return v
+}
+
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Interface, reflect.Ptr, reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,135 @@
+package codec
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+type inner struct {
+	Name  string `json:"name"`
+	Count int    `json:"count,omitempty"`
+}
+
+type outer struct {
+	inner
+	ID      int64             `json:"_id"`
+	Ratio   float64           `json:"ratio"`
+	Flags   []bool            `json:"flags"`
+	Attrs   map[string]string `json:"attrs"`
+	Ptr     *inner            `json:"ptr"`
+	Any     interface{}       `json:"any"`
+	Data    []byte            `json:"data"`
+	Result  Raw               `json:"result"`
+	JSON    json.RawMessage   `json:"json"`
+	Skipped string            `json:"-"`
+	private int
+}
+
+func TestMsgPackRoundTrip(t *testing.T) {
+	result, err := MsgPack.Marshal([]interface{}{"ok", 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := outer{
+		inner:  inner{Name: "window"},
+		ID:     -12345678901,
+		Ratio:  0.5,
+		Flags:  []bool{true, false},
+		Attrs:  map[string]string{"b": "2", "a": "1"},
+		Ptr:    &inner{Name: "nested", Count: 300},
+		Any:    map[string]interface{}{"x": float64(-1), "y": "z"},
+		Data:   []byte{0, 1, 2},
+		Result: result,
+		JSON:   json.RawMessage(`{"k":[1,2.5]}`),
+	}
+	b, err := MsgPack.Marshal(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out outer
+	if err := MsgPack.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&in, &out) {
+		t.Errorf("round trip mismatch:\n%#v\n%#v", in, out)
+	}
+	var items []interface{}
+	if err := MsgPack.Unmarshal(out.Result, &items); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(items, []interface{}{"ok", float64(1)}) {
+		t.Errorf("invalid raw value: %#v", items)
+	}
+}
+
+func TestMsgPackNumbers(t *testing.T) {
+	for _, n := range []int64{0, 1, 127, 128, 255, 256, 65535, 65536, math.MaxInt32 + 1, math.MaxInt64, -1, -32, -33, -128, -129, -32768, -32769, math.MinInt32 - 1, math.MinInt64} {
+		b, err := MsgPack.Marshal(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out int64
+		if err := MsgPack.Unmarshal(b, &out); err != nil {
+			t.Fatal(err)
+		}
+		if out != n {
+			t.Errorf("decode %d: got %d", n, out)
+		}
+	}
+}
+
+func TestMsgPackInterfaceNumbers(t *testing.T) {
+	in := map[string]interface{}{"int": 3, "uint": uint64(4), "float": 0.5, "list": []interface{}{-1, map[string]interface{}{"n": 2}}}
+	for _, c := range []Codec{JSON, MsgPack} {
+		b, err := c.Marshal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out struct {
+			Int  interface{} `json:"int"`
+			Uint interface{} `json:"uint"`
+		}
+		if err := c.Unmarshal(b, &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.Int != float64(3) || out.Uint != float64(4) {
+			t.Errorf("%s: invalid numbers: %#v, %#v", c.Name(), out.Int, out.Uint)
+		}
+		var all map[string]interface{}
+		if err := c.Unmarshal(b, &all); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]interface{}{"int": float64(3), "uint": float64(4), "float": 0.5, "list": []interface{}{float64(-1), map[string]interface{}{"n": float64(2)}}}
+		if !reflect.DeepEqual(all, want) {
+			t.Errorf("%s: invalid numbers: %#v", c.Name(), all)
+		}
+		var v interface{}
+		if err := c.Unmarshal(b, &v); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v, want) {
+			t.Errorf("%s: invalid value: %#v", c.Name(), v)
+		}
+	}
+}
+
+func TestRawJSON(t *testing.T) {
+	var v struct {
+		Result Raw `json:"_result"`
+	}
+	if err := JSON.Unmarshal([]byte(`{"_result":{"a":1}}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if string(v.Result) != `{"a":1}` {
+		t.Errorf("invalid raw value: %s", v.Result)
+	}
+	b, err := JSON.Marshal(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"_result":{"a":1}}` {
+		t.Errorf("invalid encoding: %s", b)
+	}
+}
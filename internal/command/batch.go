@@ -0,0 +1,181 @@
+package command
+
+import (
+	"log"
+	"sync"
+
+	"github.com/go-meson/meson/internal/codec"
+)
+
+// outboxItem is an encoded command waiting to be sent.
+type outboxItem struct {
+	cmd      *Command
+	msg      []byte
+	actionID int64 // non zero if a response handler waits for the command
+}
+
+// outbox serializes outgoing commands. Commands posted while a previous frame is being sent are
+// shipped together in the next frame, when the framework accepts batches.
+type outbox struct {
+	lock     sync.Mutex
+	pending  []outboxItem
+	holds    int
+	urgent   bool // a pending command has a waiting response handler
+	flushing bool
+	batch    bool // the framework accepts batch frames
+}
+
+var box = &outbox{}
+
+// Batcher collects commands to be shipped in one frame. See Batch.
+type Batcher struct {
+	err error
+}
+
+// Post adds cmd to the batch.
+func (b *Batcher) Post(cmd *Command) {
+	if b.err != nil {
+		return
+	}
+	msg, err := codec.Marshal(cmd)
+	if err != nil {
+		b.err = err
+		return
+	}
+	box.enqueue(outboxItem{cmd: cmd, msg: msg})
+}
+
+// Batch calls fn, and ships the commands posted by fn in one frame.
+//
+// Commands posted by PostMessage while fn runs are held and shipped in the same frame.
+// A synchronous request made by fn flushes the commands held so far, to keep the order.
+// If a command can not be encoded, the commands posted after it are dropped and the error is returned;
+// the ones posted before it are still shipped.
+func Batch(fn func(b *Batcher)) error {
+	box.hold()
+	defer box.release()
+	b := &Batcher{}
+	fn(b)
+	return b.err
+}
+
+func setBatchEnabled(enabled bool) {
+	box.lock.Lock()
+	defer box.lock.Unlock()
+	box.batch = enabled
+}
+
+func (o *outbox) hold() {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.holds++
+}
+
+func (o *outbox) release() {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.holds--
+	o.kick()
+}
+
+func (o *outbox) enqueue(items ...outboxItem) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	for _, item := range items {
+		if item.actionID != 0 {
+			o.urgent = true
+		}
+		recordCommand(item.cmd)
+	}
+	o.pending = append(o.pending, items...)
+	o.kick()
+}
+
+// post ships item from the calling goroutine if no frame is being sent and no batch is held,
+// and returns the error of the transport. Otherwise item is queued like enqueue, and a failure is logged.
+func (o *outbox) post(item outboxItem) error {
+	o.lock.Lock()
+	recordCommand(item.cmd)
+	if o.flushing || o.holds > 0 || len(o.pending) > 0 {
+		o.pending = append(o.pending, item)
+		o.kick()
+		o.lock.Unlock()
+		return nil
+	}
+	o.flushing = true
+	o.lock.Unlock()
+
+	err := getTransport().Send(item.msg)
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.flushing = false
+	o.kick()
+	return err
+}
+
+// kick starts the writer if needed. o.lock must be held.
+func (o *outbox) kick() {
+	if o.flushing || len(o.pending) == 0 || (o.holds > 0 && !o.urgent) {
+		return
+	}
+	o.flushing = true
+	go o.flush()
+}
+
+func (o *outbox) flush() {
+	for {
+		o.lock.Lock()
+		if len(o.pending) == 0 || (o.holds > 0 && !o.urgent) {
+			o.flushing = false
+			o.lock.Unlock()
+			return
+		}
+		items := o.pending
+		batch := o.batch
+		o.pending = nil
+		o.urgent = false
+		o.lock.Unlock()
+
+		if batch && len(items) > 1 {
+			o.sendFrame(items)
+		} else {
+			for _, item := range items {
+				o.send(item.msg, item)
+			}
+		}
+	}
+}
+
+// sendFrame ships items as an array of commands, which the framework applies in order.
+func (o *outbox) sendFrame(items []outboxItem) {
+	frame := make([]codec.Raw, len(items))
+	for i, item := range items {
+		frame[i] = item.msg
+	}
+	msg, err := codec.Marshal(frame)
+	if err != nil {
+		o.fail(err, items...)
+		return
+	}
+	o.send(msg, items...)
+}
+
+func (o *outbox) send(msg []byte, items ...outboxItem) {
+	if err := getTransport().Send(msg); err != nil {
+		o.fail(err, items...)
+	}
+}
+
+// fail reports err to the response handlers waiting for items.
+func (o *outbox) fail(err error, items ...outboxItem) {
+	for _, item := range items {
+		if item.actionID == 0 {
+			log.Printf("post message fail: %v", err)
+			continue
+		}
+		if c := takeResponseHandler(item.actionID); c != nil {
+			c(&Response{ActionID: item.actionID, Error: err.Error()})
+		}
+	}
+}
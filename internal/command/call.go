@@ -0,0 +1,55 @@
+package command
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-meson/meson/apierror"
+	"github.com/go-meson/meson/internal/binding"
+	"github.com/go-meson/meson/internal/codec"
+	"github.com/go-meson/meson/internal/object"
+	obj "github.com/go-meson/meson/object"
+)
+
+// MakeDeleteCommand makes the command which destroys the object of objType and id.
+func MakeDeleteCommand(objType obj.ObjectType, id int64) Command {
+	return Command{
+		Action: binding.ActDelete,
+		Type:   objType,
+		ID:     id,
+		Method: "_delete",
+	}
+}
+
+// Call calls method of o and waits for the result until ctx is done.
+// It fails without sending the command if o has been destroyed.
+func Call(ctx context.Context, o *object.Object, method string, args ...interface{}) (codec.Raw, error) {
+	if err := o.Alive(method); err != nil {
+		return nil, err
+	}
+	cmd := MakeCallCommand(o.ObjType, o.Id, method, args...)
+	return SendMessageContext(ctx, &cmd)
+}
+
+// Post calls method of o without waiting the result.
+// It fails without sending the command if o has been destroyed.
+func Post(o *object.Object, method string, args ...interface{}) error {
+	if err := o.Alive(method); err != nil {
+		return err
+	}
+	cmd := MakeCallCommand(o.ObjType, o.Id, method, args...)
+	return PostMessage(&cmd)
+}
+
+// Destroy destroys o in the framework, and removes it from the registry.
+func Destroy(o *object.Object) error {
+	if err := o.Alive("_delete"); err != nil {
+		return err
+	}
+	cmd := MakeDeleteCommand(o.ObjType, o.Id)
+	_, err := SendMessage(&cmd)
+	if err == nil || errors.Is(err, apierror.ErrObjectDestroyed) {
+		o.Destroyed()
+	}
+	return err
+}
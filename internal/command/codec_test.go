@@ -0,0 +1,103 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/go-meson/meson/internal/binding"
+	"github.com/go-meson/meson/internal/codec"
+	"github.com/go-meson/meson/internal/object"
+)
+
+type benchWindowOptions struct {
+	Title     string `json:"title"`
+	IconPath  string `json:"icon_path"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Frame     bool   `json:"has_frame"`
+	Resizable bool   `json:"resizable"`
+}
+
+var benchCodecs = []codec.Codec{codec.JSON, codec.MsgPack}
+
+func benchCommand() Command {
+	cmd := MakeCallCommand(binding.ObjWindow, 12, "setBounds", &benchWindowOptions{
+		Title:     "meson benchmark",
+		IconPath:  "/usr/share/icons/meson.png",
+		Width:     800,
+		Height:    600,
+		Frame:     true,
+		Resizable: true,
+	}, 1.5, "center")
+	cmd.ActionID = 123456
+	return cmd
+}
+
+// benchResponse returns a reply carrying a page capture sized payload.
+func benchResponse(c codec.Codec, size int) []byte {
+	payload := make([]byte, size)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+	result, err := c.Marshal(map[string]interface{}{"data": payload, "width": 800, "height": 600})
+	if err != nil {
+		panic(err)
+	}
+	b, err := c.Marshal(&Response{Action: binding.ActReply, ActionID: 123456, Type: binding.ObjWebContents, ID: 3, Result: result})
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestMsgPackObjectRef(t *testing.T) {
+	o := object.NewObject(103, binding.ObjWindow)
+	object.AddObject(binding.ObjWindow, o.Id, &o)
+	defer o.Destroyed()
+
+	b, err := codec.MsgPack.Marshal([]interface{}{&o})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var refs []object.Ref
+	if err := codec.MsgPack.Unmarshal(b, &refs); err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 1 || refs[0].Type != binding.ObjWindow || refs[0].ID != 103 || refs[0].Object != &o {
+		t.Errorf("invalid reference: %#v", refs)
+	}
+}
+
+func BenchmarkEncodeCommand(b *testing.B) {
+	cmd := benchCommand()
+	for _, c := range benchCodecs {
+		b.Run(c.Name(), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := c.Marshal(&cmd); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecodeResponse(b *testing.B) {
+	for _, size := range []struct {
+		name string
+		n    int
+	}{{"event", 0}, {"capture", 256 * 1024}} {
+		for _, c := range benchCodecs {
+			data := benchResponse(c, size.n)
+			b.Run(size.name+"/"+c.Name(), func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(len(data)))
+				for i := 0; i < b.N; i++ {
+					var resp Response
+					if err := c.Unmarshal(data, &resp); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -1,14 +1,18 @@
 package command
 
 import (
-	"encoding/json"
-	"errors"
+	"context"
+	"fmt"
+	"github.com/go-meson/meson/apierror"
 	"github.com/go-meson/meson/internal/binding"
+	"github.com/go-meson/meson/internal/codec"
 	"github.com/go-meson/meson/internal/object"
 	obj "github.com/go-meson/meson/object"
+	"github.com/go-meson/meson/transport"
 	"log"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type Command struct {
@@ -51,11 +55,12 @@ type Response struct {
 	Action   binding.ActionType `json:"_action"`
 	ActionID int64              `json:"_actionId"`
 	Error    string             `json:"_error"`
+	Code     string             `json:"_errorCode,omitempty"`
 	Type     obj.ObjectType     `json:"_type"`
 	ID       int64              `json:"_id"`
 	EventID  int64              `json:"_eventId"`
 	Method   string             `json:"_method,omitempty"`
-	Result   json.RawMessage    `json:"_result"`
+	Result   codec.Raw          `json:"_result"`
 }
 
 type CreateRespResult struct {
@@ -69,12 +74,116 @@ type respHandler func(resp *Response)
 
 var (
 	commandID          int64
-	APIReady           = false
+	syncCallLock       = make(chan struct{}, 1) // held by the synchronous call in flight on a non-reentrant transport
+	pendingReplies     int32                    // messages of the framework waiting for the reply on a non-reentrant transport
+	transportLock      = sync.RWMutex{}
+	currentTransport   transport.Transport
+	readyChannel       chan struct{}
+	readyHookLock      = sync.Mutex{}
+	readyHooks         []func(ctx context.Context)
+	responseLock       = sync.Mutex{}
 	responseHandler    = make(map[int64]respHandler)
-	requestChannelPool = sync.Pool{New: func() interface{} { return make(ChResp) }}
+	abandonedResponse  = make(map[int64]time.Time) // abandoned action id -> expiry
+	requestChannelPool = sync.Pool{New: func() interface{} { return make(ChResp, 1) }}
 	commonChannelPool  = sync.Pool{New: func() interface{} { return make(chan interface{}) }}
 )
 
+// SetTransport replaces the transport used to talk to the framework.
+//
+// The API becomes ready when the ready channel of t is closed.
+// A new transport starts a new lifecycle of the application.
+func SetTransport(t transport.Transport) {
+	ready := make(chan struct{})
+	resetLifecycle()
+	transportLock.Lock()
+	currentTransport = t
+	readyChannel = ready
+	transportLock.Unlock()
+	t.SetReceiveHandler(messageReceived)
+	go func() {
+		<-t.Ready()
+		transportLock.RLock()
+		active := currentTransport == t
+		transportLock.RUnlock()
+		if active {
+			negotiate(t)
+			runReadyHooks()
+			SetState(StateReady)
+			close(ready)
+		}
+	}()
+}
+
+// readyHookTimeout is how long the ready hooks can wait the framework in total.
+// It bounds a framework which does not answer, so that Ready is closed anyway.
+var readyHookTimeout = 2 * time.Second
+
+// AddReadyHook adds fn, which is called each time a transport gets ready, after the negotiation
+// and before Ready is closed. It sets up what must be in place before the application code runs,
+// so synchronous calls made by fn do not race with the calls of the application.
+// The calls made by fn must give up when ctx is done.
+func AddReadyHook(fn func(ctx context.Context)) {
+	readyHookLock.Lock()
+	defer readyHookLock.Unlock()
+	readyHooks = append(readyHooks, fn)
+}
+
+func runReadyHooks() {
+	readyHookLock.Lock()
+	hooks := readyHooks
+	readyHookLock.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), readyHookTimeout)
+	defer cancel()
+	for _, fn := range hooks {
+		fn(ctx)
+	}
+}
+
+// Ready returns a channel which is closed once the current transport is ready.
+func Ready() <-chan struct{} {
+	transportLock.RLock()
+	defer transportLock.RUnlock()
+	return readyChannel
+}
+
+// TransportReady returns a channel which is closed once the framework behind the current transport is ready.
+// Unlike Ready, it does not wait the negotiation.
+func TransportReady() <-chan struct{} {
+	return getTransport().Ready()
+}
+
+func getTransport() transport.Transport {
+	transportLock.RLock()
+	defer transportLock.RUnlock()
+	return currentTransport
+}
+
+// isReentrant reports whether the current transport serves requests while a reply is pending.
+func isReentrant() bool {
+	r, ok := getTransport().(transport.Reentrant)
+	return ok && r.Reentrant()
+}
+
+// enterSyncCall waits until the synchronous call of another goroutine ends, or ctx is done.
+// It fails with apierror.ErrUnsupported while the framework waits for a reply,
+// since the framework can not serve the call until the reply is returned.
+func enterSyncCall(ctx context.Context, cmd *Command) error {
+	if atomic.LoadInt32(&pendingReplies) > 0 {
+		return apierror.New(apierror.CodeUnsupported, cmd.Type, cmd.ID, cmd.Method,
+			"synchronous call while the framework waits for a reply; use an asynchronous call")
+	}
+	select {
+	case syncCallLock <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return contextError(ctx, cmd)
+	}
+}
+
+func leaveSyncCall() {
+	<-syncCallLock
+}
+
 func GetCommonChan() chan interface{} {
 	return commonChannelPool.Get().(chan interface{})
 }
@@ -121,97 +230,258 @@ func MakeUnregEventCommand(objType obj.ObjectType, id int64, eventID int64) Comm
 	}
 }
 
+// MakeCancelCommand makes the command which asks the framework to abandon the request of actionID.
+// The framework accepts it only if it has answered so in the negotiation.
+func MakeCancelCommand(actionID int64) Command {
+	return Command{
+		Action: binding.ActCall,
+		Type:   binding.ObjApp,
+		ID:     binding.ObjStaticID,
+		Method: "_cancelRequest",
+		Args:   []interface{}{actionID},
+	}
+}
+
+// CheckResponse returns an *apierror.Error if resp reports a failure.
 func CheckResponse(resp *Response) error {
-	if resp.Error != "" {
-		return errors.New(resp.Error)
+	if resp.Error == "" && resp.Code == "" {
+		return nil
+	}
+	code := apierror.Code(resp.Code)
+	if code == "" {
+		code = apierror.CodeUnknown
+	}
+	return apierror.New(code, resp.Type, resp.ID, resp.Method, resp.Error)
+}
+
+// checkResponse is same as CheckResponse, but the error tells the target of cmd.
+func checkResponse(cmd *Command, resp *Response) error {
+	err := CheckResponse(resp)
+	if e, ok := err.(*apierror.Error); ok {
+		e.ObjType, e.ID, e.Method = cmd.Type, cmd.ID, cmd.Method
+	}
+	return err
+}
+
+// CheckReady returns an apierror.ErrNotReady error about method of objType if the API is not ready yet,
+// or no longer usable since the application has exited.
+func CheckReady(objType obj.ObjectType, method string) error {
+	switch CurrentState() {
+	case StateStarting:
+		return apierror.New(apierror.CodeNotReady, objType, binding.ObjStaticID, method, "")
+	case StateExited:
+		return apierror.New(apierror.CodeNotReady, objType, binding.ObjStaticID, method, "application has exited")
 	}
 	return nil
 }
 
+// contextError wraps the error of ctx. A deadline is reported as apierror.ErrTimeout.
+func contextError(ctx context.Context, cmd *Command) error {
+	err := ctx.Err()
+	if err != context.DeadlineExceeded {
+		return err
+	}
+	return &apierror.Error{ObjType: cmd.Type, ID: cmd.ID, Method: cmd.Method, Code: apierror.CodeTimeout, Err: err}
+}
+
+// PostMessage sends cmd without waiting a response.
+//
+// Consecutive posts are shipped together in one frame when the framework accepts batches.
+// The error of the transport is returned when cmd is sent at once. When cmd waits behind another frame
+// or in a Batch, it is sent later and a failure of the transport is only logged.
 func PostMessage(cmd *Command) error {
-	bytes, err := json.Marshal(cmd)
+	bytes, err := codec.Marshal(cmd)
 	if err != nil {
 		return err
 	}
-	binding.PostMessage(bytes)
-	return nil
+	return box.post(outboxItem{cmd: cmd, msg: bytes})
 }
 
 func sendMessage(cmd *Command, actionID int64, handler respHandler) error {
 	cmd.ActionID = actionID
-	bytes, err := json.Marshal(cmd)
+	bytes, err := codec.Marshal(cmd)
 	if err != nil {
 		return err
 	}
+	responseLock.Lock()
 	responseHandler[actionID] = handler
-	binding.PostMessage(bytes)
+	responseLock.Unlock()
+	box.enqueue(outboxItem{cmd: cmd, msg: bytes, actionID: actionID})
 	return nil
 }
 
+func takeResponseHandler(actionID int64) respHandler {
+	responseLock.Lock()
+	defer responseLock.Unlock()
+	if c, ok := responseHandler[actionID]; ok {
+		delete(responseHandler, actionID)
+		return c
+	}
+	return nil
+}
+
+// abandonedResponseTTL is how long a late reply of an abandoned request is expected,
+// when the framework does not acknowledge the cancel or does not accept it.
+const abandonedResponseTTL = 5 * time.Minute
+
+// abandonResponse removes the pending handler of actionID.
+// It returns false if the response has already been delivered.
+func abandonResponse(actionID int64) bool {
+	responseLock.Lock()
+	defer responseLock.Unlock()
+	if _, ok := responseHandler[actionID]; !ok {
+		return false
+	}
+	delete(responseHandler, actionID)
+	now := time.Now()
+	for id, expiry := range abandonedResponse {
+		if now.After(expiry) {
+			delete(abandonedResponse, id)
+		}
+	}
+	abandonedResponse[actionID] = now.Add(abandonedResponseTTL)
+	return true
+}
+
+// cancelRequest asks the framework to abandon the request of actionID.
+// The framework replies to the requests in order, so once it acknowledges the cancel,
+// no reply of the request comes any more.
+func cancelRequest(actionID int64) error {
+	cancel := MakeCancelCommand(actionID)
+	return SendMessageAsync(&cancel, func(r *Response) {
+		if CheckResponse(r) == nil {
+			takeAbandonedResponse(actionID)
+		}
+	})
+}
+
+func takeAbandonedResponse(actionID int64) bool {
+	responseLock.Lock()
+	defer responseLock.Unlock()
+	if _, ok := abandonedResponse[actionID]; ok {
+		delete(abandonedResponse, actionID)
+		return true
+	}
+	return false
+}
+
 func SendMessageAsync(cmd *Command, handler respHandler) error {
 	actionID := atomic.AddInt64(&commandID, 1)
 	return sendMessage(cmd, actionID, handler)
 }
 
-func SendMessage(cmd *Command) (json.RawMessage, error) {
-	if !binding.TryEnterSendMessage() {
-		return nil, errors.New("invalid context")
+func SendMessage(cmd *Command) (codec.Raw, error) {
+	return SendMessageContext(context.Background(), cmd)
+}
+
+// SendMessageContext sends cmd and waits for the response until ctx is done.
+//
+// When ctx is done first, the pending request is abandoned and the error of ctx is returned.
+// A deadline is reported as apierror.ErrTimeout, which also matches context.DeadlineExceeded.
+// A failure reported by the framework is returned as *apierror.Error.
+//
+// Unless the transport is reentrant, a synchronous call made while the framework waits for
+// the reply to an event, such as from a preventable event handler, can not be served.
+// It fails with apierror.ErrUnsupported; use an asynchronous call such as SendMessageAsync instead.
+// Other synchronous calls on such a transport are made one at a time, and wait for each other until ctx is done.
+// The cgo transport is reentrant only when the framework exports MesonApiDispatchRequest.
+func SendMessageContext(ctx context.Context, cmd *Command) (codec.Raw, error) {
+	if ctx.Err() != nil {
+		return nil, contextError(ctx, cmd)
+	}
+	if !isReentrant() {
+		if err := enterSyncCall(ctx, cmd); err != nil {
+			return nil, err
+		}
+		defer leaveSyncCall()
 	}
-	defer binding.LeaveSendMessage()
 	actionID := atomic.AddInt64(&commandID, 1)
 	ch := getRespChan()
 	if err := sendMessage(cmd, actionID, func(r *Response) {
 		ch <- r
 	}); err != nil {
+		releaseRespChan(ch)
 		return nil, err
 	}
-	resp := <-ch
+	var resp *Response
+	select {
+	case resp = <-ch:
+	case <-ctx.Done():
+		if abandonResponse(actionID) {
+			// ch is never written, so it can be reused.
+			releaseRespChan(ch)
+			if isCancelSupported() {
+				if err := cancelRequest(actionID); err != nil {
+					log.Printf("cancel request %d fail: %v", actionID, err)
+				}
+			}
+			return nil, contextError(ctx, cmd)
+		}
+		// the response has been delivered concurrently.
+		resp = <-ch
+	}
 	releaseRespChan(ch)
-	if err := CheckResponse(resp); err != nil {
+	if err := checkResponse(cmd, resp); err != nil {
 		return nil, err
 	}
 	return resp.Result, nil
 }
 
-func messageReceived(id int64, msg string, needReply bool) string {
-	var resp Response
-	err := json.Unmarshal([]byte(msg), &resp)
-	if err != nil {
-		panic("json decode fail.")
+func messageReceived(id int64, msg []byte, needReply bool) []byte {
+	if needReply && !isReentrant() {
+		atomic.AddInt32(&pendingReplies, 1)
+		defer atomic.AddInt32(&pendingReplies, -1)
 	}
+	var resp Response
 	var result interface{}
+	if err := codec.Current().Unmarshal(msg, &resp); err != nil {
+		ReportFault(&Fault{Kind: FaultDecode, Message: msg, Err: err})
+	} else {
+		recordResponse(id, &resp, needReply)
+		result = dispatchResponse(&resp, msg, needReply)
+	}
+
+	if needReply {
+		recordReply(id, result)
+		r, _ := codec.Marshal(result)
+		return r
+	}
+	return nil
+}
+
+// dispatchResponse delivers resp to the waiting handler or the target object.
+// It returns the reply to the framework if needReply.
+func dispatchResponse(resp *Response, msg []byte, needReply bool) interface{} {
 	switch resp.Action {
 	case binding.ActReply:
-		if c, ok := responseHandler[resp.ActionID]; ok {
-			delete(responseHandler, resp.ActionID)
-			c(&resp)
-		} else {
-			log.Fatalf("invalid response: %#v\n", resp)
+		if c := takeResponseHandler(resp.ActionID); c != nil {
+			c(resp)
+		} else if !takeAbandonedResponse(resp.ActionID) {
+			ReportFault(&Fault{Kind: FaultUnknownResponse, ObjType: resp.Type, ID: resp.ID, ActionID: resp.ActionID, Message: msg})
 		}
 	case binding.ActEvent:
-		if o := object.GetObject(resp.Type, resp.ID); o == nil {
-			log.Fatalf("object not found: %d/%d", resp.Type, resp.ID)
-		} else {
-			if !needReply {
-				go func() {
-					o.EmitEvent(o, resp.EventID, resp.Result)
-				}()
-			} else {
-				b, _ := o.EmitEvent(o, resp.EventID, resp.Result)
-				result = b
-			}
+		o := object.GetObject(resp.Type, resp.ID)
+		if o == nil {
+			ReportFault(&Fault{Kind: FaultUnknownObject, ObjType: resp.Type, ID: resp.ID, EventID: resp.EventID, Message: msg})
+			return nil
+		}
+		if !needReply {
+			events.dispatch(o, resp)
+			return nil
+		}
+		// the framework waits for the reply, so the event is delivered at once.
+		return emitEvent(o, resp)
+	case binding.ActDelete:
+		// the object is destroyed by the framework, or Destroy is confirmed.
+		if o := object.GetObject(resp.Type, resp.ID); o != nil {
+			o.Destroyed()
 		}
 	default:
-		log.Panicf("invalid action: %#v", resp)
+		ReportFault(&Fault{Kind: FaultInvalidAction, ObjType: resp.Type, ID: resp.ID, Message: msg, Err: fmt.Errorf("action %d", resp.Action)})
 	}
-
-	if needReply {
-		r, _ := json.Marshal(result)
-		return string(r)
-	}
-	return ""
+	return nil
 }
 
 func init() {
-	binding.SetMessageReceiveHandler(messageReceived)
+	SetTransport(binding.Transport{})
 }
@@ -0,0 +1,717 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-meson/meson/apierror"
+	"github.com/go-meson/meson/internal/binding"
+	"github.com/go-meson/meson/internal/codec"
+	"github.com/go-meson/meson/internal/object"
+	obj "github.com/go-meson/meson/object"
+	"github.com/go-meson/meson/transport"
+)
+
+// newTestLoopback returns a ready loopback transport, which passes requests other than the negotiation to serve.
+func newTestLoopback(t *testing.T, serve func(lb *transport.Loopback, cmd *Command)) *transport.Loopback {
+	lb := transport.NewLoopback()
+	SetTransport(lb)
+	go func() {
+		for req := range lb.Requests() {
+			var cmd Command
+			if err := json.Unmarshal(req, &cmd); err != nil {
+				t.Errorf("invalid request: %s", req)
+				continue
+			}
+			if cmd.Method == "_negotiate" {
+				postReply(t, lb, cmd.ActionID, `{"cancel":true}`)
+				continue
+			}
+			serve(lb, &cmd)
+		}
+	}()
+	lb.SetReady()
+	<-Ready()
+	return lb
+}
+
+func postReply(t *testing.T, lb *transport.Loopback, actionID int64, result string) {
+	resp := Response{Action: binding.ActReply, ActionID: actionID, Result: codec.Raw(result)}
+	b, _ := codec.Marshal(&resp)
+	if _, err := lb.Post(0, b, false); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSendMessageContextTimeout(t *testing.T) {
+	requests := make(chan Command, 2)
+	lb := newTestLoopback(t, func(lb *transport.Loopback, cmd *Command) {
+		requests <- *cmd
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	cmd := MakeCallCommand(binding.ObjWindow, 1, "hang")
+	_, err := SendMessageContext(ctx, &cmd)
+	if !errors.Is(err, apierror.ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sent := <-requests
+	cancelReq := <-requests
+	if cancelReq.Method != "_cancelRequest" {
+		t.Fatalf("cancel request is not sent: %#v", cancelReq)
+	}
+	if _, ok := responseHandler[sent.ActionID]; ok {
+		t.Error("pending handler is not removed")
+	}
+
+	// a late reply for the abandoned request is dropped.
+	postReply(t, lb, sent.ActionID, `true`)
+	if _, ok := abandonedResponse[sent.ActionID]; ok {
+		t.Error("abandoned response is not consumed")
+	}
+}
+
+func TestCancelAcknowledged(t *testing.T) {
+	acked := make(chan struct{})
+	newTestLoopback(t, func(lb *transport.Loopback, cmd *Command) {
+		if cmd.Method == "_cancelRequest" {
+			postReply(t, lb, cmd.ActionID, `null`)
+			close(acked)
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	cmd := MakeCallCommand(binding.ObjWindow, 1, "hang")
+	if _, err := SendMessageContext(ctx, &cmd); !errors.Is(err, apierror.ErrTimeout) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-acked
+	responseLock.Lock()
+	n := len(abandonedResponse)
+	responseLock.Unlock()
+	if n != 0 {
+		t.Errorf("%d abandoned responses are left after the cancel is acknowledged", n)
+	}
+}
+
+func TestCancelUnsupported(t *testing.T) {
+	lb := transport.NewLoopback()
+	SetTransport(lb)
+	requests := make(chan Command, 2)
+	go func() {
+		for req := range lb.Requests() {
+			var cmd Command
+			if err := json.Unmarshal(req, &cmd); err != nil {
+				t.Errorf("invalid request: %s", req)
+				continue
+			}
+			if cmd.Method == "_negotiate" {
+				postReply(t, lb, cmd.ActionID, `{}`)
+				continue
+			}
+			requests <- cmd
+		}
+	}()
+	lb.SetReady()
+	<-Ready()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	cmd := MakeCallCommand(binding.ObjWindow, 1, "hang")
+	if _, err := SendMessageContext(ctx, &cmd); !errors.Is(err, apierror.ErrTimeout) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sent := <-requests
+	// a late reply is still dropped without the cancel.
+	postReply(t, lb, sent.ActionID, `true`)
+	select {
+	case r := <-requests:
+		t.Errorf("%s is sent to the framework which does not accept it", r.Method)
+	default:
+	}
+	if takeAbandonedResponse(sent.ActionID) {
+		t.Error("abandoned response is not consumed")
+	}
+}
+
+type syncCallCallback struct {
+	t *testing.T
+}
+
+func (c syncCallCallback) Call(o obj.ObjectRef, arg codec.Raw) (bool, error) {
+	cmd := MakeCallCommand(o.GetObjectType(), o.GetID(), "isDevToolsOpened")
+	r, err := SendMessage(&cmd)
+	if err != nil {
+		c.t.Errorf("SendMessage in event handler fail: %v", err)
+		return false, err
+	}
+	var b bool
+	err = codec.Decode(r, &b)
+	return b, err
+}
+
+func TestSendMessageInsidePreventableEvent(t *testing.T) {
+	lb := newTestLoopback(t, func(lb *transport.Loopback, cmd *Command) {
+		postReply(t, lb, cmd.ActionID, `true`)
+	})
+
+	o := object.NewObject(100, binding.ObjWindow)
+	object.AddObject(binding.ObjWindow, o.Id, &o)
+	defer o.Destroyed()
+	o.AddEventHandler(1, syncCallCallback{t: t})
+
+	evt := Response{Action: binding.ActEvent, Type: binding.ObjWindow, ID: o.Id, EventID: 1}
+	b, _ := json.Marshal(&evt)
+	result, err := lb.Post(0, b, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != "true" {
+		t.Errorf("unexpected reply: %s", result)
+	}
+}
+
+// nonReentrant is a Loopback which can not serve requests while a reply is pending,
+// as the cgo transport without MesonApiDispatchRequest.
+type nonReentrant struct {
+	*transport.Loopback
+}
+
+func (nonReentrant) Reentrant() bool {
+	return false
+}
+
+type nestedCallCallback struct {
+	errs chan error
+}
+
+func (c nestedCallCallback) Call(o obj.ObjectRef, arg codec.Raw) (bool, error) {
+	cmd := MakeCallCommand(o.GetObjectType(), o.GetID(), "isDevToolsOpened")
+	_, err := SendMessage(&cmd)
+	c.errs <- err
+	return true, nil
+}
+
+func TestSendMessageInsidePreventableEventNonReentrant(t *testing.T) {
+	lb := transport.NewLoopback()
+	SetTransport(nonReentrant{lb})
+	go func() {
+		for req := range lb.Requests() {
+			var cmd Command
+			if err := json.Unmarshal(req, &cmd); err != nil {
+				t.Errorf("invalid request: %s", req)
+				continue
+			}
+			postReply(t, lb, cmd.ActionID, `null`)
+		}
+	}()
+	lb.SetReady()
+	<-Ready()
+
+	o := object.NewObject(101, binding.ObjWindow)
+	object.AddObject(binding.ObjWindow, o.Id, &o)
+	defer o.Destroyed()
+	errs := make(chan error, 1)
+	o.AddEventHandler(1, nestedCallCallback{errs: errs})
+
+	evt := Response{Action: binding.ActEvent, Type: binding.ObjWindow, ID: o.Id, EventID: 1}
+	b, _ := json.Marshal(&evt)
+	result, err := lb.Post(0, b, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != "true" {
+		t.Errorf("unexpected reply: %s", result)
+	}
+	if err := <-errs; !errors.Is(err, apierror.ErrUnsupported) {
+		t.Errorf("nested synchronous call must be unsupported: %v", err)
+	}
+
+	// the call is served once the reply is returned.
+	cmd := MakeCallCommand(binding.ObjWindow, o.Id, "isDevToolsOpened")
+	if _, err := SendMessage(&cmd); err != nil {
+		t.Errorf("synchronous call fail: %v", err)
+	}
+}
+
+func TestConcurrentSendMessageNonReentrant(t *testing.T) {
+	lb := transport.NewLoopback()
+	SetTransport(nonReentrant{lb})
+	go func() {
+		for req := range lb.Requests() {
+			var cmd Command
+			if err := json.Unmarshal(req, &cmd); err != nil {
+				t.Errorf("invalid request: %s", req)
+				continue
+			}
+			// the reply comes later, so the other calls are made meanwhile.
+			go func(actionID int64) {
+				time.Sleep(5 * time.Millisecond)
+				postReply(t, lb, actionID, `true`)
+			}(cmd.ActionID)
+		}
+	}()
+	lb.SetReady()
+	<-Ready()
+
+	errs := make(chan error, 4)
+	for i := int64(1); i <= 4; i++ {
+		go func(id int64) {
+			cmd := MakeCallCommand(binding.ObjWindow, id, "isVisible")
+			_, err := SendMessage(&cmd)
+			errs <- err
+		}(i)
+	}
+	for i := 0; i < 4; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("concurrent synchronous call fail: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	syncCallLock <- struct{}{}
+	cmd := MakeCallCommand(binding.ObjWindow, 1, "isVisible")
+	_, err := SendMessageContext(ctx, &cmd)
+	<-syncCallLock
+	if !errors.Is(err, apierror.ErrTimeout) {
+		t.Errorf("waiting for another call must end with ctx: %v", err)
+	}
+}
+
+func TestNegotiateMsgPack(t *testing.T) {
+	lb := transport.NewLoopback()
+	lb.EnableBinary()
+	SetTransport(lb)
+	defer codec.SetCurrent(codec.JSON)
+	go func() {
+		c := codec.JSON
+		for req := range lb.Requests() {
+			var cmd Command
+			if err := c.Unmarshal(req, &cmd); err != nil {
+				t.Errorf("invalid request: %v", err)
+				continue
+			}
+			var result interface{} = "pong"
+			if cmd.Method == "_negotiate" {
+				result = &negotiateResult{Codec: codec.MsgPack.Name()}
+			}
+			r, _ := c.Marshal(result)
+			b, _ := c.Marshal(&Response{Action: binding.ActReply, ActionID: cmd.ActionID, Result: r})
+			if _, err := lb.Post(0, b, false); err != nil {
+				t.Error(err)
+			}
+			// the framework switches the codec after the negotiation reply.
+			if cmd.Method == "_negotiate" {
+				c = codec.MsgPack
+			}
+		}
+	}()
+	lb.SetReady()
+	<-Ready()
+
+	if codec.Current() != codec.MsgPack {
+		t.Fatalf("negotiated codec is %s", codec.Current().Name())
+	}
+	cmd := MakeCallCommand(binding.ObjApp, binding.ObjStaticID, "ping")
+	r, err := SendMessage(&cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s string
+	if err := codec.Decode(r, &s); err != nil || s != "pong" {
+		t.Errorf("unexpected result: %q, %v", s, err)
+	}
+}
+
+// notNegotiable is a Loopback whose framework does not know the negotiation,
+// as the cgo transport without MesonApiSetBinaryHandler.
+type notNegotiable struct {
+	*transport.Loopback
+}
+
+func (notNegotiable) Negotiable() bool {
+	return false
+}
+
+func TestNegotiateSkipped(t *testing.T) {
+	lb := transport.NewLoopback()
+	lb.EnableBinary()
+	SetTransport(notNegotiable{lb})
+	methods := make(chan string, 1)
+	go func() {
+		for req := range lb.Requests() {
+			var cmd Command
+			if err := json.Unmarshal(req, &cmd); err != nil {
+				t.Errorf("invalid request: %s", req)
+				continue
+			}
+			methods <- cmd.Method
+			postReply(t, lb, cmd.ActionID, `null`)
+		}
+	}()
+	lb.SetReady()
+	<-Ready()
+
+	box.lock.Lock()
+	batch := box.batch
+	box.lock.Unlock()
+	if codec.Current() != codec.JSON || batch {
+		t.Errorf("the wire format is changed without the negotiation")
+	}
+	cmd := MakeCallCommand(binding.ObjApp, binding.ObjStaticID, "ping")
+	if _, err := SendMessage(&cmd); err != nil {
+		t.Fatal(err)
+	}
+	if m := <-methods; m != "ping" {
+		t.Errorf("%s is sent to the framework which does not negotiate", m)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	lb := transport.NewLoopback()
+	SetTransport(lb)
+	frames := make(chan []byte, 1)
+	go func() {
+		for req := range lb.Requests() {
+			var cmd Command
+			if err := json.Unmarshal(req, &cmd); err != nil {
+				frames <- req
+				continue
+			}
+			if cmd.Method == "_negotiate" {
+				postReply(t, lb, cmd.ActionID, `{"batch":true}`)
+			}
+		}
+	}()
+	lb.SetReady()
+	<-Ready()
+
+	err := Batch(func(b *Batcher) {
+		for i := int64(1); i <= 3; i++ {
+			cmd := MakeCallCommand(binding.ObjWindow, i, "show")
+			b.Post(&cmd)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cmds []Command
+	if err := json.Unmarshal(<-frames, &cmds); err != nil {
+		t.Fatalf("invalid batch frame: %v", err)
+	}
+	if len(cmds) != 3 {
+		t.Fatalf("unexpected batch size: %d", len(cmds))
+	}
+	for i, cmd := range cmds {
+		if cmd.ID != int64(i+1) {
+			t.Errorf("command %d is out of order: %#v", i, cmd)
+		}
+	}
+}
+
+func TestBatchSyncCall(t *testing.T) {
+	lb := transport.NewLoopback()
+	SetTransport(lb)
+	seen := make(chan int64, 8)
+	go func() {
+		for req := range lb.Requests() {
+			var cmds []Command
+			if err := json.Unmarshal(req, &cmds); err != nil {
+				var cmd Command
+				if err := json.Unmarshal(req, &cmd); err != nil {
+					t.Errorf("invalid request: %v", err)
+					continue
+				}
+				cmds = []Command{cmd}
+			}
+			for _, cmd := range cmds {
+				if cmd.Method == "_negotiate" {
+					postReply(t, lb, cmd.ActionID, `{"batch":true}`)
+					continue
+				}
+				seen <- cmd.ID
+				if cmd.ActionID != 0 {
+					postReply(t, lb, cmd.ActionID, `true`)
+				}
+			}
+		}
+	}()
+	lb.SetReady()
+	<-Ready()
+
+	err := Batch(func(b *Batcher) {
+		cmd := MakeCallCommand(binding.ObjWindow, 1, "show")
+		b.Post(&cmd)
+		call := MakeCallCommand(binding.ObjWindow, 2, "isVisible")
+		if _, err := SendMessage(&call); err != nil {
+			t.Error(err)
+		}
+		cmd = MakeCallCommand(binding.ObjWindow, 3, "hide")
+		b.Post(&cmd)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for want := int64(1); want <= 3; want++ {
+		if id := <-seen; id != want {
+			t.Errorf("command %d is sent instead of %d", id, want)
+		}
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	o := object.NewObject(101, binding.ObjWindow)
+	object.AddObject(binding.ObjWindow, o.Id, &o)
+	defer o.Destroyed()
+	o.AddEventHandler(1, syncCallCallback{t: t})
+	ping := func() {
+		cmd := MakeCallCommand(binding.ObjApp, binding.ObjStaticID, "ping")
+		if _, err := SendMessage(&cmd); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	SetRecorder(NewRecorder(&buf))
+	lb := newTestLoopback(t, func(lb *transport.Loopback, cmd *Command) {
+		postReply(t, lb, cmd.ActionID, `true`)
+	})
+	ping()
+	evt := Response{Action: binding.ActEvent, Type: binding.ObjWindow, ID: o.Id, EventID: 1}
+	b, _ := json.Marshal(&evt)
+	if _, err := lb.Post(7, b, true); err != nil {
+		t.Fatal(err)
+	}
+	SetRecorder(nil)
+
+	p, err := NewReplayer(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetTransport(p)
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Run(context.Background())
+	}()
+	<-Ready()
+	ping()
+	if err := <-done; err != nil {
+		t.Errorf("replay fail: %v", err)
+	}
+}
+
+func TestReplayArgs(t *testing.T) {
+	setTitle := func(title string) {
+		cmd := MakeCallCommand(binding.ObjWindow, 1, "setTitle", title)
+		if err := PostMessage(&cmd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	SetRecorder(NewRecorder(&buf))
+	newTestLoopback(t, func(lb *transport.Loopback, cmd *Command) {})
+	setTitle("recorded")
+	SetRecorder(nil)
+
+	for _, c := range []struct {
+		title string
+		ok    bool
+	}{{"recorded", true}, {"changed", false}} {
+		p, err := NewReplayer(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Timeout = 200 * time.Millisecond
+		SetTransport(p)
+		done := make(chan error, 1)
+		go func() {
+			done <- p.Run(context.Background())
+		}()
+		<-Ready()
+		setTitle(c.title)
+		if err := <-done; (err == nil) != c.ok {
+			t.Errorf("replay with %q: %v", c.title, err)
+		}
+	}
+}
+
+func TestSendMessageError(t *testing.T) {
+	newTestLoopback(t, func(lb *transport.Loopback, cmd *Command) {
+		resp := Response{Action: binding.ActReply, ActionID: cmd.ActionID, Error: "window is closed", Code: "object_destroyed"}
+		b, _ := codec.Marshal(&resp)
+		if _, err := lb.Post(0, b, false); err != nil {
+			t.Error(err)
+		}
+	})
+
+	cmd := MakeCallCommand(binding.ObjWindow, 3, "focus")
+	_, err := SendMessage(&cmd)
+	if !errors.Is(err, apierror.ErrObjectDestroyed) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var e *apierror.Error
+	if !errors.As(err, &e) || e.ObjType != binding.ObjWindow || e.ID != 3 || e.Method != "focus" {
+		t.Errorf("error does not tell the target: %#v", err)
+	}
+}
+
+func TestFaultUnknownObject(t *testing.T) {
+	lb := newTestLoopback(t, func(lb *transport.Loopback, cmd *Command) {})
+	faults := make(chan *Fault, 1)
+	SetFaultHandler(func(f *Fault) FaultAction {
+		faults <- f
+		return FaultDrop
+	})
+	defer SetFaultHandler(nil)
+
+	evt := Response{Action: binding.ActEvent, Type: binding.ObjWindow, ID: 9999, EventID: 1}
+	b, _ := json.Marshal(&evt)
+	result, err := lb.Post(0, b, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != "null" {
+		t.Errorf("unexpected reply: %s", result)
+	}
+	if f := <-faults; f.Kind != FaultUnknownObject || f.ID != 9999 {
+		t.Errorf("unexpected fault: %v", f)
+	}
+	if DefaultFaultHandler(&Fault{Kind: FaultUnknownObject}) == FaultAbort {
+		t.Error("event on unknown object aborts by default")
+	}
+}
+
+type recordCallback chan int
+
+func (c recordCallback) Call(o obj.ObjectRef, arg codec.Raw) (bool, error) {
+	var n int
+	if err := codec.Decode(arg, &n); err != nil {
+		return false, err
+	}
+	if n < 0 {
+		panic("handler panic")
+	}
+	time.Sleep(time.Millisecond)
+	c <- n
+	return false, nil
+}
+
+func TestDispatchSerialPerObject(t *testing.T) {
+	lb := newTestLoopback(t, func(lb *transport.Loopback, cmd *Command) {})
+	faults := make(chan *Fault, 1)
+	SetFaultHandler(func(f *Fault) FaultAction {
+		faults <- f
+		return FaultDrop
+	})
+	defer SetFaultHandler(nil)
+
+	o := object.NewObject(102, binding.ObjWindow)
+	object.AddObject(binding.ObjWindow, o.Id, &o)
+	defer o.Destroyed()
+	received := make(recordCallback, 10)
+	o.AddEventHandler(1, received)
+	post := func(n int) {
+		evt := Response{Action: binding.ActEvent, Type: binding.ObjWindow, ID: o.Id, EventID: 1, Result: codec.Raw(fmt.Sprint(n))}
+		b, _ := json.Marshal(&evt)
+		if _, err := lb.Post(0, b, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	post(-1)
+	for i := 0; i < 5; i++ {
+		post(i)
+	}
+	for i := 0; i < 5; i++ {
+		if n := <-received; n != i {
+			t.Fatalf("event %d is delivered as %d", i, n)
+		}
+	}
+	if f := <-faults; f.Kind != FaultHandlerPanic {
+		t.Errorf("unexpected fault: %v", f)
+	}
+	if d := QueueDepth(); d != 0 {
+		t.Errorf("unexpected queue depth: %d", d)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	newTestLoopback(t, func(lb *transport.Loopback, cmd *Command) {})
+	if s := CurrentState(); s != StateReady {
+		t.Fatalf("unexpected state: %s", s)
+	}
+	ctx := RootContext()
+	done := make(chan error, 1)
+	go func() {
+		done <- WaitState(context.Background(), StateQuitting)
+	}()
+
+	SetState(StateQuitting)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Err() == nil {
+		t.Error("root context is not cancelled when quitting")
+	}
+	SetState(StateReady)
+	if s := CurrentState(); s != StateQuitting {
+		t.Errorf("state moves backward: %s", s)
+	}
+	if err := CheckReady(binding.ObjWindow, "_create"); err != nil {
+		t.Errorf("API is not usable while quitting: %v", err)
+	}
+}
+
+func TestReadyHook(t *testing.T) {
+	defer func(d time.Duration) { readyHookTimeout = d }(readyHookTimeout)
+	readyHookTimeout = 10 * time.Millisecond
+	hooked := make(chan State, 1)
+	hookErrs := make(chan error, 1)
+	armed := int32(1)
+	// hooks are never removed, so it acts only on the transport of this test.
+	AddReadyHook(func(ctx context.Context) {
+		if !atomic.CompareAndSwapInt32(&armed, 1, 0) {
+			return
+		}
+		hooked <- CurrentState()
+		// the framework does not answer, but Ready is closed anyway.
+		cmd := MakeCallCommand(binding.ObjApp, binding.ObjStaticID, "hang")
+		_, err := SendMessageContext(ctx, &cmd)
+		hookErrs <- err
+	})
+	canceled := make(chan struct{})
+	newTestLoopback(t, func(lb *transport.Loopback, cmd *Command) {
+		if cmd.Method == "_cancelRequest" {
+			postReply(t, lb, cmd.ActionID, `null`)
+			close(canceled)
+		}
+	})
+	if err := <-hookErrs; !errors.Is(err, apierror.ErrTimeout) {
+		t.Errorf("unanswered call in the hook must time out: %v", err)
+	}
+	<-canceled
+	select {
+	case s := <-hooked:
+		if s != StateStarting {
+			t.Errorf("hook is called in %s state", s)
+		}
+	default:
+		t.Fatal("hook is not called before ready")
+	}
+	if err := CheckReady(binding.ObjWindow, "_create"); err != nil {
+		t.Errorf("ready API is rejected: %v", err)
+	}
+	SetState(StateExited)
+	if err := CheckReady(binding.ObjWindow, "_create"); !errors.Is(err, apierror.ErrNotReady) {
+		t.Errorf("exited API is accepted: %v", err)
+	}
+}
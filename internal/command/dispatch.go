@@ -0,0 +1,129 @@
+package command
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-meson/meson/internal/object"
+	obj "github.com/go-meson/meson/object"
+)
+
+// DispatchPolicy decides how events which need no reply are delivered to the handlers.
+type DispatchPolicy int
+
+const (
+	// DispatchSerialPerObject delivers the events of each object one by one, in the order they arrive.
+	// Events of different objects are delivered concurrently.
+	DispatchSerialPerObject DispatchPolicy = iota
+	// DispatchSerial delivers all events one by one, in the order they arrive.
+	DispatchSerial
+	// DispatchPool delivers events by a bounded number of workers, in no particular order.
+	DispatchPool
+)
+
+type laneKey struct {
+	policy  DispatchPolicy
+	objType obj.ObjectType
+	id      int64
+}
+
+// lane is a FIFO of events, which is drained by up to max goroutines.
+type lane struct {
+	jobs    []func()
+	runners int
+	max     int
+}
+
+type dispatcher struct {
+	lock    sync.Mutex
+	policy  DispatchPolicy
+	workers int
+	lanes   map[laneKey]*lane
+	depth   int64
+}
+
+var events = &dispatcher{lanes: make(map[laneKey]*lane), workers: runtime.NumCPU()}
+
+// SetDispatchPolicy changes the dispatch policy of events arriving from now on.
+// Events queued before the change are not ordered with the ones after it.
+// workers is the number of workers for DispatchPool; the number of CPUs if it is not positive.
+func SetDispatchPolicy(policy DispatchPolicy, workers int) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	events.lock.Lock()
+	defer events.lock.Unlock()
+	events.policy = policy
+	events.workers = workers
+}
+
+// QueueDepth returns the number of events waiting to be delivered.
+func QueueDepth() int {
+	return int(atomic.LoadInt64(&events.depth))
+}
+
+func (d *dispatcher) dispatch(o object.ObjectRefInternal, resp *Response) {
+	atomic.AddInt64(&d.depth, 1)
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	key, max := laneKey{policy: d.policy}, 1
+	switch d.policy {
+	case DispatchSerialPerObject:
+		key.objType, key.id = resp.Type, resp.ID
+	case DispatchPool:
+		max = d.workers
+	}
+	l, ok := d.lanes[key]
+	if !ok {
+		l = &lane{max: max}
+		d.lanes[key] = l
+	}
+	l.jobs = append(l.jobs, func() {
+		emitEvent(o, resp)
+	})
+	if l.runners < l.max {
+		l.runners++
+		go d.drain(key, l)
+	}
+}
+
+func (d *dispatcher) drain(key laneKey, l *lane) {
+	for {
+		d.lock.Lock()
+		if len(l.jobs) == 0 {
+			l.runners--
+			if l.runners == 0 {
+				delete(d.lanes, key)
+			}
+			d.lock.Unlock()
+			return
+		}
+		job := l.jobs[0]
+		l.jobs[0] = nil
+		l.jobs = l.jobs[1:]
+		d.lock.Unlock()
+		atomic.AddInt64(&d.depth, -1)
+		job()
+	}
+}
+
+// emitEvent delivers resp to the handlers of o. A panic in a handler is reported as a fault.
+func emitEvent(o object.ObjectRefInternal, resp *Response) (prevent bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			ReportFault(&Fault{
+				Kind:    FaultHandlerPanic,
+				ObjType: resp.Type,
+				ID:      resp.ID,
+				EventID: resp.EventID,
+				Err:     fmt.Errorf("%v\n%s", r, debug.Stack()),
+			})
+			prevent = false
+		}
+	}()
+	prevent, _ = o.EmitEvent(o, resp.EventID, resp.Result)
+	return prevent
+}
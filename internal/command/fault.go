@@ -0,0 +1,131 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	obj "github.com/go-meson/meson/object"
+)
+
+// FaultKind is the kind of a protocol fault.
+type FaultKind int
+
+const (
+	// FaultDecode is a message from the framework which can not be decoded.
+	FaultDecode FaultKind = iota
+	// FaultUnknownResponse is a reply to no pending request.
+	FaultUnknownResponse
+	// FaultUnknownObject is an event aimed at an object missing from the registry,
+	// such as an event which arrives after the object is destroyed.
+	FaultUnknownObject
+	// FaultInvalidAction is a message with an unknown action.
+	FaultInvalidAction
+	// FaultEventPayload is an event payload which can not be decoded for the handler.
+	FaultEventPayload
+	// FaultHandlerPanic is a panic recovered from an event handler.
+	FaultHandlerPanic
+)
+
+func (k FaultKind) String() string {
+	switch k {
+	case FaultDecode:
+		return "decode"
+	case FaultUnknownResponse:
+		return "unknown response"
+	case FaultUnknownObject:
+		return "unknown object"
+	case FaultInvalidAction:
+		return "invalid action"
+	case FaultEventPayload:
+		return "event payload"
+	case FaultHandlerPanic:
+		return "handler panic"
+	}
+	return fmt.Sprintf("FaultKind(%d)", int(k))
+}
+
+// FaultAction is what to do with a fault.
+type FaultAction int
+
+const (
+	// FaultDrop ignores the fault silently.
+	FaultDrop FaultAction = iota
+	// FaultLog logs the fault and ignores it.
+	FaultLog
+	// FaultAbort logs the fault and exits the process.
+	FaultAbort
+)
+
+// Fault is a message from the framework which Go can not handle.
+type Fault struct {
+	Kind     FaultKind
+	ObjType  obj.ObjectType // type of the target object, if known
+	ID       int64          // id of the target object, if known
+	ActionID int64          // action id of a reply, if known
+	EventID  int64          // event id of an event, if known
+	Message  []byte         // the raw message
+	Err      error          // underlying cause, if any
+}
+
+func (f *Fault) Error() string {
+	switch f.Kind {
+	case FaultDecode:
+		return fmt.Sprintf("meson fault: %s: %v", f.Kind, f.Err)
+	case FaultUnknownResponse:
+		return fmt.Sprintf("meson fault: %s: action id %d", f.Kind, f.ActionID)
+	case FaultUnknownObject:
+		return fmt.Sprintf("meson fault: %s: %d/%d (event id %d)", f.Kind, f.ObjType, f.ID, f.EventID)
+	}
+	if f.Err != nil {
+		return fmt.Sprintf("meson fault: %s: %v", f.Kind, f.Err)
+	}
+	return fmt.Sprintf("meson fault: %s", f.Kind)
+}
+
+// Unwrap returns the underlying cause.
+func (f *Fault) Unwrap() error {
+	return f.Err
+}
+
+// FaultHandler decides what to do with a fault.
+type FaultHandler func(f *Fault) FaultAction
+
+// DefaultFaultHandler logs events on unknown objects, undecodable event payloads and panics in handlers,
+// and aborts on the other faults.
+func DefaultFaultHandler(f *Fault) FaultAction {
+	switch f.Kind {
+	case FaultUnknownObject, FaultEventPayload, FaultHandlerPanic:
+		return FaultLog
+	}
+	return FaultAbort
+}
+
+var (
+	faultLock                 = sync.RWMutex{}
+	faultHandler FaultHandler = DefaultFaultHandler
+)
+
+// SetFaultHandler replaces the fault handler. A nil handler restores DefaultFaultHandler.
+func SetFaultHandler(handler FaultHandler) {
+	if handler == nil {
+		handler = DefaultFaultHandler
+	}
+	faultLock.Lock()
+	defer faultLock.Unlock()
+	faultHandler = handler
+}
+
+// ReportFault passes f to the fault handler, and does the action it decides.
+func ReportFault(f *Fault) {
+	faultLock.RLock()
+	handler := faultHandler
+	faultLock.RUnlock()
+	switch handler(f) {
+	case FaultDrop:
+	case FaultLog:
+		log.Print(f)
+	default:
+		log.Fatal(f)
+	}
+}
@@ -0,0 +1,109 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// State is a phase of the application lifecycle. It only moves forward.
+type State int
+
+const (
+	// StateStarting is the state until the framework is ready.
+	StateStarting State = iota
+	// StateReady is the state while the API is usable.
+	StateReady
+	// StateQuitting is the state once the application has decided to quit.
+	StateQuitting
+	// StateExited is the state after the application has quit.
+	StateExited
+)
+
+func (s State) String() string {
+	switch s {
+	case StateStarting:
+		return "starting"
+	case StateReady:
+		return "ready"
+	case StateQuitting:
+		return "quitting"
+	case StateExited:
+		return "exited"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+type lifecycleState struct {
+	lock    sync.Mutex
+	state   State
+	changed chan struct{} // closed when state changes
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+var lifecycle = newLifecycle()
+
+func newLifecycle() *lifecycleState {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &lifecycleState{changed: make(chan struct{}), ctx: ctx, cancel: cancel}
+}
+
+// resetLifecycle starts a new lifecycle for a new transport.
+func resetLifecycle() {
+	l := newLifecycle()
+	lifecycle.lock.Lock()
+	defer lifecycle.lock.Unlock()
+	lifecycle.state = l.state
+	close(lifecycle.changed)
+	lifecycle.changed = l.changed
+	lifecycle.cancel()
+	lifecycle.ctx, lifecycle.cancel = l.ctx, l.cancel
+}
+
+// CurrentState returns the current state of the application.
+func CurrentState() State {
+	lifecycle.lock.Lock()
+	defer lifecycle.lock.Unlock()
+	return lifecycle.state
+}
+
+// SetState moves the application to s. Moving backward is ignored.
+// The root context is cancelled when the application starts quitting.
+func SetState(s State) {
+	lifecycle.lock.Lock()
+	defer lifecycle.lock.Unlock()
+	if s <= lifecycle.state {
+		return
+	}
+	lifecycle.state = s
+	close(lifecycle.changed)
+	lifecycle.changed = make(chan struct{})
+	if s >= StateQuitting {
+		lifecycle.cancel()
+	}
+}
+
+// WaitState waits until the application reaches s, or ctx is done.
+func WaitState(ctx context.Context, s State) error {
+	for {
+		lifecycle.lock.Lock()
+		state, changed := lifecycle.state, lifecycle.changed
+		lifecycle.lock.Unlock()
+		if state >= s {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// RootContext returns the context which is cancelled when the application starts quitting.
+func RootContext() context.Context {
+	lifecycle.lock.Lock()
+	defer lifecycle.lock.Unlock()
+	return lifecycle.ctx
+}
@@ -0,0 +1,85 @@
+package command
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-meson/meson/internal/binding"
+	"github.com/go-meson/meson/internal/codec"
+	"github.com/go-meson/meson/transport"
+)
+
+// negotiateTimeout is how long to wait the framework's answer for the negotiation.
+// A negotiable framework answers at once, so it only bounds a broken one.
+const negotiateTimeout = 500 * time.Millisecond
+
+type negotiateOpt struct {
+	Codecs []string `json:"codecs"` // acceptable codecs in order of preference
+	Batch  bool     `json:"batch"`  // whether Go can send batch frames
+}
+
+type negotiateResult struct {
+	Codec  string `json:"codec"`
+	Batch  bool   `json:"batch"`  // whether the framework accepts batch frames
+	Cancel bool   `json:"cancel"` // whether the framework accepts _cancelRequest
+}
+
+// cancelSupported is 1 if the framework has answered it accepts _cancelRequest.
+var cancelSupported int32
+
+func setCancelSupported(supported bool) {
+	var v int32
+	if supported {
+		v = 1
+	}
+	atomic.StoreInt32(&cancelSupported, v)
+}
+
+func isCancelSupported() bool {
+	return atomic.LoadInt32(&cancelSupported) != 0
+}
+
+func makeNegotiateCommand(opt *negotiateOpt) Command {
+	return MakeCallCommand(binding.ObjApp, binding.ObjStaticID, "_negotiate", opt)
+}
+
+// negotiate agrees the wire format with the framework on startup.
+//
+// The framework is asked only if t is transport.Negotiable; the contract is described
+// with MesonApiSetBinaryHandler in meson.h. The negotiation itself is encoded in JSON,
+// and JSON stays in use unless the framework answers with the name of another known codec.
+// Batch frames and _cancelRequest are sent only if the framework answers it accepts them.
+func negotiate(t transport.Transport) {
+	codec.SetCurrent(codec.JSON)
+	setBatchEnabled(false)
+	setCancelSupported(false)
+	if n, ok := t.(transport.Negotiable); !ok || !n.Negotiable() {
+		return
+	}
+	opt := negotiateOpt{Batch: true}
+	if b, ok := t.(transport.Binary); ok && b.Binary() {
+		opt.Codecs = append(opt.Codecs, codec.MsgPack.Name())
+	}
+	opt.Codecs = append(opt.Codecs, codec.JSON.Name())
+
+	ctx, cancel := context.WithTimeout(context.Background(), negotiateTimeout)
+	defer cancel()
+	cmd := makeNegotiateCommand(&opt)
+	r, err := SendMessageContext(ctx, &cmd)
+	if err != nil {
+		log.Printf("negotiation fail, use json: %v", err)
+		return
+	}
+	var result negotiateResult
+	if err := codec.Decode(r, &result); err != nil {
+		log.Printf("invalid negotiation result, use json: %v", err)
+		return
+	}
+	if c, ok := codec.Lookup(result.Codec); ok {
+		codec.SetCurrent(c)
+	}
+	setBatchEnabled(result.Batch)
+	setCancelSupported(result.Cancel)
+}
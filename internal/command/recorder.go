@@ -0,0 +1,157 @@
+package command
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-meson/meson/internal/codec"
+)
+
+// Direction is the direction of a recorded message.
+type Direction string
+
+const (
+	// DirectionOut is a message from Go to the framework.
+	DirectionOut Direction = "out"
+	// DirectionIn is a message from the framework to Go.
+	DirectionIn Direction = "in"
+)
+
+// RecordKind is the kind of a recorded message.
+type RecordKind string
+
+const (
+	// RecordCommand is a Command sent by Go.
+	RecordCommand RecordKind = "command"
+	// RecordResponse is a Response or an event sent by the framework.
+	RecordResponse RecordKind = "response"
+	// RecordReply is the reply of Go to an event which needs a reply.
+	RecordReply RecordKind = "reply"
+)
+
+// Record is a line of a recorded session. Values are always stored in JSON, whatever the codec in use.
+type Record struct {
+	Time      time.Time       `json:"time"`
+	Direction Direction       `json:"dir"`
+	Kind      RecordKind      `json:"kind"`
+	MessageID int64           `json:"messageId,omitempty"` // id of the framework message which needs a reply
+	NeedReply bool            `json:"needReply,omitempty"`
+	Command   *Command        `json:"command,omitempty"`
+	Response  *Response       `json:"response,omitempty"`
+	Reply     json.RawMessage `json:"reply,omitempty"`
+}
+
+// Recorder writes the messages exchanged with the framework as JSON lines.
+type Recorder struct {
+	lock   sync.Mutex
+	enc    *json.Encoder
+	closer io.Closer
+}
+
+// NewRecorder creates a Recorder writing to w.
+func NewRecorder(w io.Writer) *Recorder {
+	r := &Recorder{enc: json.NewEncoder(w)}
+	if c, ok := w.(io.Closer); ok {
+		r.closer = c
+	}
+	return r
+}
+
+// CreateRecorder creates a Recorder writing to the file of path.
+func CreateRecorder(path string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewRecorder(f), nil
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (r *Recorder) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.closer == nil {
+		return nil
+	}
+	err := r.closer.Close()
+	r.closer = nil
+	return err
+}
+
+func (r *Recorder) write(rec *Record) {
+	rec.Time = time.Now()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if err := r.enc.Encode(rec); err != nil {
+		log.Printf("record message fail: %v", err)
+	}
+}
+
+var (
+	recorderLock    = sync.RWMutex{}
+	currentRecorder *Recorder
+)
+
+// SetRecorder starts recording the session into r. A nil r stops recording.
+//
+// The previous recorder is not closed.
+func SetRecorder(r *Recorder) {
+	recorderLock.Lock()
+	defer recorderLock.Unlock()
+	currentRecorder = r
+}
+
+// CurrentRecorder returns the recorder in use, or nil.
+func CurrentRecorder() *Recorder {
+	recorderLock.RLock()
+	defer recorderLock.RUnlock()
+	return currentRecorder
+}
+
+func recordCommand(cmd *Command) {
+	if r := CurrentRecorder(); r != nil {
+		r.write(&Record{Direction: DirectionOut, Kind: RecordCommand, Command: cmd})
+	}
+}
+
+func recordResponse(id int64, resp *Response, needReply bool) {
+	r := CurrentRecorder()
+	if r == nil {
+		return
+	}
+	rec := *resp
+	rec.Result = jsonValue(resp.Result)
+	r.write(&Record{Direction: DirectionIn, Kind: RecordResponse, MessageID: id, NeedReply: needReply, Response: &rec})
+}
+
+func recordReply(id int64, result interface{}) {
+	r := CurrentRecorder()
+	if r == nil {
+		return
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("record reply fail: %v", err)
+		return
+	}
+	r.write(&Record{Direction: DirectionOut, Kind: RecordReply, MessageID: id, Reply: b})
+}
+
+// jsonValue converts raw, which is encoded by the codec in use, into JSON.
+func jsonValue(raw codec.Raw) codec.Raw {
+	c := codec.Current()
+	if c == codec.JSON || len(raw) == 0 {
+		return raw
+	}
+	var v interface{}
+	if err := c.Unmarshal(raw, &v); err != nil {
+		log.Printf("convert %s value into json fail: %v", c.Name(), err)
+		return nil
+	}
+	b, _ := json.Marshal(v)
+	return b
+}
@@ -0,0 +1,352 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-meson/meson/internal/binding"
+	"github.com/go-meson/meson/internal/codec"
+	"github.com/go-meson/meson/transport"
+)
+
+// DefaultReplayTimeout is how long a Replayer waits for each recorded command by default.
+const DefaultReplayTimeout = 5 * time.Second
+
+// Replayer is a Transport which plays a recorded session back as the framework.
+//
+// Responses and events are delivered in the recorded order, after the Go code has sent the commands
+// recorded before them. Commands are matched by action, object type, object id, method and arguments;
+// commands sent between two framework messages may come in any order.
+// The replay always uses the JSON codec, whatever the codec of the recorded session.
+type Replayer struct {
+	// Timeout is how long to wait for each recorded command.
+	Timeout time.Duration
+
+	records   []Record
+	sent      chan []byte
+	ready     chan struct{}
+	readyOnce sync.Once
+	lock      sync.RWMutex
+	handler   transport.ReceiveHandler
+}
+
+// NewReplayer creates a Replayer of the session recorded in r.
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("invalid record at line %d: %v", line, err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &Replayer{
+		Timeout: DefaultReplayTimeout,
+		records: records,
+		sent:    make(chan []byte, 1024),
+		ready:   make(chan struct{}),
+	}, nil
+}
+
+// OpenReplayer creates a Replayer of the session recorded in the file of path.
+func OpenReplayer(path string) (*Replayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewReplayer(f)
+}
+
+// Send implements transport.Transport.
+func (p *Replayer) Send(msg []byte) error {
+	p.sent <- msg
+	return nil
+}
+
+// SetReceiveHandler implements transport.Transport.
+func (p *Replayer) SetReceiveHandler(handler transport.ReceiveHandler) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.handler = handler
+}
+
+// Ready implements transport.Transport. It is closed by Run.
+func (p *Replayer) Ready() <-chan struct{} {
+	return p.ready
+}
+
+// Reentrant implements transport.Reentrant.
+func (p *Replayer) Reentrant() bool {
+	return true
+}
+
+// Negotiable implements transport.Negotiable. It reports whether the recorded session negotiated.
+func (p *Replayer) Negotiable() bool {
+	for i := range p.records {
+		if cmd := p.records[i].Command; cmd != nil && cmd.Method == "_negotiate" {
+			return true
+		}
+	}
+	return false
+}
+
+type replayState struct {
+	actionIDs map[int64]int64 // recorded action id to the one of this run
+	replies   map[int64]chan []byte
+	unmatched []*Command
+}
+
+// Run makes the transport ready and plays the session back.
+// It returns the first difference between the recorded session and the behavior of the Go code.
+func (p *Replayer) Run(ctx context.Context) error {
+	p.readyOnce.Do(func() {
+		close(p.ready)
+	})
+	s := &replayState{
+		actionIDs: make(map[int64]int64),
+		replies:   make(map[int64]chan []byte),
+	}
+	for i := range p.records {
+		rec := &p.records[i]
+		var err error
+		switch rec.Kind {
+		case RecordCommand:
+			err = p.expectCommand(ctx, s, rec.Command)
+		case RecordResponse:
+			err = p.postResponse(s, rec)
+		case RecordReply:
+			err = p.expectReply(ctx, s, rec)
+		default:
+			err = fmt.Errorf("unknown record kind %q", rec.Kind)
+		}
+		if err != nil {
+			return fmt.Errorf("record %d: %v", i+1, err)
+		}
+	}
+	if len(s.unmatched) > 0 {
+		return fmt.Errorf("unexpected command: %s", describeCommand(s.unmatched[0]))
+	}
+	return nil
+}
+
+func (p *Replayer) expectCommand(ctx context.Context, s *replayState, want *Command) error {
+	if want == nil {
+		return fmt.Errorf("command record without command")
+	}
+	wantArgs := normalizeArgs(want.Args)
+	if want.Method == "_cancelRequest" {
+		wantArgs = s.mapCancelArgs(wantArgs)
+	}
+	match := func(cmd *Command) bool {
+		if cmd.Action != want.Action || cmd.Type != want.Type || cmd.ID != want.ID || cmd.Method != want.Method {
+			return false
+		}
+		// the offered codecs depend on the transport, which is not the recorded one.
+		if want.Method != "_negotiate" && !reflect.DeepEqual(normalizeArgs(cmd.Args), wantArgs) {
+			return false
+		}
+		if want.ActionID != 0 {
+			s.actionIDs[want.ActionID] = cmd.ActionID
+		}
+		return true
+	}
+	for i, cmd := range s.unmatched {
+		if match(cmd) {
+			s.unmatched = append(s.unmatched[:i], s.unmatched[i+1:]...)
+			return nil
+		}
+	}
+	timer := time.NewTimer(p.Timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case msg := <-p.sent:
+			cmds, err := decodeFrame(msg)
+			if err != nil {
+				return err
+			}
+			found := false
+			for _, cmd := range cmds {
+				if !found && match(cmd) {
+					found = true
+					continue
+				}
+				s.unmatched = append(s.unmatched, cmd)
+			}
+			if found {
+				return nil
+			}
+		case <-timer.C:
+			if len(s.unmatched) > 0 {
+				return fmt.Errorf("command %s is not sent, got %s", describeCommand(want), describeCommand(s.unmatched[0]))
+			}
+			return fmt.Errorf("command %s is not sent", describeCommand(want))
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (p *Replayer) postResponse(s *replayState, rec *Record) error {
+	if rec.Response == nil {
+		return fmt.Errorf("response record without response")
+	}
+	resp := *rec.Response
+	if resp.Action == binding.ActReply {
+		id, ok := s.actionIDs[resp.ActionID]
+		if !ok {
+			return fmt.Errorf("reply to unknown action %d", resp.ActionID)
+		}
+		resp.ActionID = id
+	}
+	if p.isNegotiateReply(rec) {
+		resp.Result = jsonNegotiateResult(resp.Result)
+	}
+	msg, err := json.Marshal(&resp)
+	if err != nil {
+		return err
+	}
+	p.lock.RLock()
+	handler := p.handler
+	p.lock.RUnlock()
+	if handler == nil {
+		return fmt.Errorf("receive handler is not registered")
+	}
+	if !rec.NeedReply {
+		handler(rec.MessageID, msg, false)
+		return nil
+	}
+	ch := make(chan []byte, 1)
+	s.replies[rec.MessageID] = ch
+	go func() {
+		ch <- handler(rec.MessageID, msg, true)
+	}()
+	return nil
+}
+
+// isNegotiateReply reports whether rec is the reply to the negotiation, which is the first request.
+func (p *Replayer) isNegotiateReply(rec *Record) bool {
+	if rec.Response.Action != binding.ActReply {
+		return false
+	}
+	for i := range p.records {
+		if cmd := p.records[i].Command; cmd != nil && cmd.Method == "_negotiate" {
+			return cmd.ActionID == rec.Response.ActionID
+		}
+	}
+	return false
+}
+
+// jsonNegotiateResult keeps the negotiated options but the codec, since the replay uses JSON.
+func jsonNegotiateResult(r codec.Raw) codec.Raw {
+	var result negotiateResult
+	if err := json.Unmarshal(r, &result); err != nil {
+		return r
+	}
+	result.Codec = codec.JSON.Name()
+	b, _ := json.Marshal(&result)
+	return b
+}
+
+func (p *Replayer) expectReply(ctx context.Context, s *replayState, rec *Record) error {
+	ch, ok := s.replies[rec.MessageID]
+	if !ok {
+		return fmt.Errorf("reply to unknown message %d", rec.MessageID)
+	}
+	delete(s.replies, rec.MessageID)
+	timer := time.NewTimer(p.Timeout)
+	defer timer.Stop()
+	var reply []byte
+	select {
+	case reply = <-ch:
+	case <-timer.C:
+		return fmt.Errorf("message %d is not replied", rec.MessageID)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	var got, want interface{}
+	if err := json.Unmarshal(reply, &got); err != nil {
+		return fmt.Errorf("invalid reply to message %d: %v", rec.MessageID, err)
+	}
+	if err := json.Unmarshal(rec.Reply, &want); err != nil {
+		return fmt.Errorf("invalid recorded reply to message %d: %v", rec.MessageID, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		return fmt.Errorf("reply to message %d is %s, recorded %s", rec.MessageID, reply, rec.Reply)
+	}
+	return nil
+}
+
+// decodeFrame decodes a message from Go, which is a command or a batch of commands.
+func decodeFrame(msg []byte) ([]*Command, error) {
+	if trimmed := bytes.TrimSpace(msg); len(trimmed) > 0 && trimmed[0] == '[' {
+		var frame []codec.Raw
+		if err := json.Unmarshal(trimmed, &frame); err != nil {
+			return nil, fmt.Errorf("invalid batch frame: %v", err)
+		}
+		cmds := make([]*Command, 0, len(frame))
+		for _, m := range frame {
+			var cmd Command
+			if err := json.Unmarshal(m, &cmd); err != nil {
+				return nil, fmt.Errorf("invalid command: %v", err)
+			}
+			cmds = append(cmds, &cmd)
+		}
+		return cmds, nil
+	}
+	var cmd Command
+	if err := json.Unmarshal(msg, &cmd); err != nil {
+		return nil, fmt.Errorf("invalid command: %v", err)
+	}
+	return []*Command{&cmd}, nil
+}
+
+// normalizeArgs converts args to the form decoded from JSON, so that recorded and sent arguments compare equal.
+func normalizeArgs(args interface{}) interface{} {
+	b, err := json.Marshal(args)
+	if err != nil {
+		return args
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return args
+	}
+	return v
+}
+
+// mapCancelArgs replaces the recorded action id in the arguments of a cancel command by the one of this run.
+func (s *replayState) mapCancelArgs(args interface{}) interface{} {
+	a, ok := args.([]interface{})
+	if !ok || len(a) != 1 {
+		return args
+	}
+	id, ok := a[0].(float64)
+	if !ok {
+		return args
+	}
+	if actual, ok := s.actionIDs[int64(id)]; ok {
+		return []interface{}{float64(actual)}
+	}
+	return args
+}
+
+func describeCommand(cmd *Command) string {
+	return fmt.Sprintf("%s(%d/%d)", cmd.Method, cmd.Type, cmd.ID)
+}
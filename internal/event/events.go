@@ -1,30 +1,70 @@
 package event
 
 import (
-	"encoding/json"
-	evt "github.com/go-meson/meson/event"
+	"context"
+
+	"github.com/go-meson/meson/internal/codec"
 	"github.com/go-meson/meson/internal/command"
 	"github.com/go-meson/meson/internal/object"
 	obj "github.com/go-meson/meson/object"
 )
 
-func AddCallback(o *object.Object, event string, callback object.CallbackInterface) error {
-	cmd := command.MakeRegEventCommand(o.ObjType, o.Id, event)
+// AddCallback adds callback for the framework event named event of o.
+//
+// The registration to the framework is shared by the handlers of the same event,
+// and it is released when the last handler is unsubscribed.
+func AddCallback(o *object.Object, event string, callback object.CallbackInterface) (*object.Subscription, error) {
+	return AddCallbackContext(context.Background(), o, event, callback)
+}
+
+// AddCallbackContext is same as AddCallback, but gives up waiting the framework when ctx is done.
+func AddCallbackContext(ctx context.Context, o *object.Object, event string, callback object.CallbackInterface) (*object.Subscription, error) {
+	if err := o.Alive("_regevent"); err != nil {
+		return nil, err
+	}
+	if eventID, id, ok := o.AddNamedEventHandler(event, callback); ok {
+		return newSubscription(o, eventID, id), nil
+	}
 
-	resp, err := command.SendMessage(&cmd)
+	cmd := command.MakeRegEventCommand(o.ObjType, o.Id, event)
+	resp, err := command.SendMessageContext(ctx, &cmd)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var eventID int64
-	err = json.Unmarshal(resp, &eventID)
+	err = codec.Decode(resp, &eventID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	o.AddRegisterdCallback(eventID, callback)
+	recorded, id, registered := o.RegisterNamedEvent(event, eventID, callback)
+	if !registered {
+		// registered by another subscriber meanwhile.
+		unregister(o, eventID)
+	}
+	return newSubscription(o, recorded, id), nil
+}
 
-	return nil
+// AddTemporaryCallback adds callback for the temporary event of eventID, made by MakeTemporaryEvents.
+func AddTemporaryCallback(o *object.Object, eventID int64, callback object.CallbackInterface) *object.Subscription {
+	return newSubscription(o, eventID, o.AddEventHandler(eventID, callback))
+}
+
+func newSubscription(o *object.Object, eventID int64, id int64) *object.Subscription {
+	return object.NewSubscription(func() {
+		if o.RemoveEventHandler(eventID, id) {
+			unregister(o, eventID)
+		}
+	})
+}
+
+func unregister(o *object.Object, eventID int64) {
+	if o.IsDestroyed() {
+		return
+	}
+	cmd := command.MakeUnregEventCommand(o.ObjType, o.Id, eventID)
+	command.PostMessage(&cmd)
 }
 
 type TempEventItem struct {
@@ -40,7 +80,7 @@ func MakeTempEventAsync(o *object.Object, num int, handler func([]TempEventItem,
 			return
 		}
 		var events []TempEventItem
-		if err := json.Unmarshal(r.Result, &events); err != nil {
+		if err := codec.Decode(r.Result, &events); err != nil {
 			handler(nil, err)
 			return
 		}
@@ -65,27 +105,19 @@ func MakeTemporaryEvents(o *object.Object, num int) ([]TempEventItem, error) {
 	return ret.([]TempEventItem), nil
 }
 
-func DeleteRegisterdCallback(o *object.Object, eventID int64, no int) {
-	if o.DelRegisterdCallback(eventID, no) {
-		cmd := command.MakeUnregEventCommand(o.ObjType, o.Id, eventID)
-		command.PostMessage(&cmd)
-	}
-
-}
-
 type CommonCallbackItem struct {
-	F evt.CommonCallbackHandler
+	F func(obj.ObjectRef)
 }
 
-func (p CommonCallbackItem) Call(o obj.ObjectRef, arg json.RawMessage) (bool, error) {
+func (p CommonCallbackItem) Call(o obj.ObjectRef, arg codec.Raw) (bool, error) {
 	p.F(o)
 	return false, nil
 }
 
 type CommonPreventableCallbackItem struct {
-	F evt.CommonPreventableCallbackHandler
+	F func(obj.ObjectRef) bool
 }
 
-func (p CommonPreventableCallbackItem) Call(o obj.ObjectRef, arg json.RawMessage) (bool, error) {
+func (p CommonPreventableCallbackItem) Call(o obj.ObjectRef, arg codec.Raw) (bool, error) {
 	return p.F(o), nil
 }
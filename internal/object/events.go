@@ -0,0 +1,136 @@
+package object
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type handlerEntry struct {
+	id       int64
+	callback CallbackInterface
+}
+
+// eventRegistry holds the event handlers of an object. It is shared by copies of the object.
+type eventRegistry struct {
+	lock     sync.RWMutex
+	handlers map[int64][]handlerEntry
+	names    map[string]int64 // name of an event registered to the framework to its event id
+}
+
+var handlerID int64
+
+func newEventRegistry() *eventRegistry {
+	return &eventRegistry{
+		handlers: make(map[int64][]handlerEntry),
+		names:    make(map[string]int64),
+	}
+}
+
+// AddEventHandler adds callback for eventID, and returns the id of the handler to remove it.
+// Handlers are called in the order they are added.
+func (o *Object) AddEventHandler(eventID int64, callback CallbackInterface) int64 {
+	r := o.events
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.add(eventID, callback)
+}
+
+func (r *eventRegistry) add(eventID int64, callback CallbackInterface) int64 {
+	id := atomic.AddInt64(&handlerID, 1)
+	r.handlers[eventID] = append(r.handlers[eventID], handlerEntry{id: id, callback: callback})
+	return id
+}
+
+// RemoveEventHandler removes the handler of id for eventID.
+// It reports whether the removed handler was the last one for eventID,
+// in which case the registration of the event name is forgotten too.
+func (o *Object) RemoveEventHandler(eventID int64, id int64) bool {
+	r := o.events
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	entries, ok := r.handlers[eventID]
+	if !ok {
+		return false
+	}
+	for i, e := range entries {
+		if e.id != id {
+			continue
+		}
+		rest := make([]handlerEntry, 0, len(entries)-1)
+		rest = append(rest, entries[:i]...)
+		rest = append(rest, entries[i+1:]...)
+		if len(rest) > 0 {
+			r.handlers[eventID] = rest
+			return false
+		}
+		delete(r.handlers, eventID)
+		for name, recorded := range r.names {
+			if recorded == eventID {
+				delete(r.names, name)
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// EventHandlers returns the handlers for eventID at the moment.
+func (o *Object) EventHandlers(eventID int64) []CallbackInterface {
+	r := o.events
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	entries := r.handlers[eventID]
+	if len(entries) == 0 {
+		return nil
+	}
+	callbacks := make([]CallbackInterface, len(entries))
+	for i, e := range entries {
+		callbacks[i] = e.callback
+	}
+	return callbacks
+}
+
+// AddNamedEventHandler adds callback for the event name, if it is registered to the framework.
+func (o *Object) AddNamedEventHandler(name string, callback CallbackInterface) (eventID int64, id int64, ok bool) {
+	r := o.events
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if eventID, ok = r.names[name]; !ok {
+		return 0, 0, false
+	}
+	return eventID, r.add(eventID, callback), true
+}
+
+// RegisterNamedEvent records eventID as the registration of the event name, and adds callback for it.
+// If the name has been registered meanwhile, callback is added to the recorded event,
+// and registered is false: the caller should release eventID.
+func (o *Object) RegisterNamedEvent(name string, eventID int64, callback CallbackInterface) (recorded int64, id int64, registered bool) {
+	r := o.events
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	recorded, ok := r.names[name]
+	if !ok {
+		r.names[name] = eventID
+		recorded = eventID
+	}
+	return recorded, r.add(recorded, callback), !ok
+}
+
+// Subscription is a registered event handler.
+type Subscription struct {
+	once    sync.Once
+	release func()
+}
+
+// NewSubscription creates a Subscription which calls release on the first Unsubscribe.
+func NewSubscription(release func()) *Subscription {
+	return &Subscription{release: release}
+}
+
+// Unsubscribe removes the handler. Calling it again does nothing.
+func (s *Subscription) Unsubscribe() {
+	if s == nil {
+		return
+	}
+	s.once.Do(s.release)
+}
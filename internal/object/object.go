@@ -2,29 +2,47 @@ package object
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
+	"github.com/go-meson/meson/apierror"
+	"github.com/go-meson/meson/internal/codec"
 	obj "github.com/go-meson/meson/object"
 	"log"
+	"sort"
 	"sync"
+	"sync/atomic"
 )
 
 type CallbackInterface interface {
-	Call(obj.ObjectRef, json.RawMessage) (bool, error)
+	Call(obj.ObjectRef, codec.Raw) (bool, error)
 }
 
-type eventRegisters map[int64][]CallbackInterface
+// ResultObserver is a callback which is also told whether the event is prevented,
+// after all the handlers of the event are called.
+type ResultObserver interface {
+	CallbackInterface
+	Observe(sender obj.ObjectRef, arg codec.Raw, prevented bool)
+}
 
 type Object struct {
-	Id              int64
-	ObjType         obj.ObjectType
-	registerdEvents eventRegisters
-	UserData        interface{}
+	Id       int64
+	ObjType  obj.ObjectType
+	events   *eventRegistry
+	UserData interface{}
+	state    *objectState
+}
+
+// objectState is the state of an object shared by its copies, such as the one decoded from a result.
+type objectState struct {
+	destroyed  int32
+	lock       sync.Mutex
+	dependents []ObjectRefInternal // objects destroyed together with the object
 }
 
 type ObjectRefInternal interface {
 	obj.ObjectRef
-	EmitEvent(sender ObjectRefInternal, eventID int64, arg json.RawMessage) (bool, error)
+	EmitEvent(sender ObjectRefInternal, eventID int64, arg codec.Raw) (bool, error)
+	Destroyed()
+	Base() *Object
 }
 
 var (
@@ -34,9 +52,10 @@ var (
 
 func NewObject(id int64, objType obj.ObjectType) Object {
 	return Object{
-		Id:              id,
-		ObjType:         objType,
-		registerdEvents: make(eventRegisters),
+		Id:      id,
+		ObjType: objType,
+		events:  newEventRegistry(),
+		state:   &objectState{},
 	}
 }
 
@@ -55,6 +74,23 @@ func AddObject(t obj.ObjectType, id int64, o ObjectRefInternal) {
 	tm[id] = o
 }
 
+// LoadOrAddObject returns the registered object of t and id, or registers the one made by create.
+func LoadOrAddObject(t obj.ObjectType, id int64, create func() ObjectRefInternal) ObjectRefInternal {
+	lock.Lock()
+	defer lock.Unlock()
+	tm, ok := objects[t]
+	if !ok {
+		tm = make(map[int64]ObjectRefInternal)
+		objects[t] = tm
+	}
+	if o, ok := tm[id]; ok {
+		return o
+	}
+	o := create()
+	tm[id] = o
+	return o
+}
+
 func GetObject(t obj.ObjectType, id int64) ObjectRefInternal {
 	var r ObjectRefInternal
 	lock.RLock()
@@ -67,6 +103,21 @@ func GetObject(t obj.ObjectType, id int64) ObjectRefInternal {
 	return r
 }
 
+// Objects returns the registered objects of type t, in the order of id.
+func Objects(t obj.ObjectType) []ObjectRefInternal {
+	lock.RLock()
+	tm := objects[t]
+	r := make([]ObjectRefInternal, 0, len(tm))
+	for _, o := range tm {
+		r = append(r, o)
+	}
+	lock.RUnlock()
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].GetID() < r[j].GetID()
+	})
+	return r
+}
+
 func (o *Object) GetID() int64 {
 	return o.Id
 }
@@ -75,37 +126,87 @@ func (o *Object) GetObjectType() obj.ObjectType {
 	return o.ObjType
 }
 
-func (o *Object) EmitEvent(sender ObjectRefInternal, eventID int64, args json.RawMessage) (bool, error) {
+// Base returns o itself. It gives the Object embedded in a wrapper such as Window.
+func (o *Object) Base() *Object {
+	return o
+}
+
+func (o *Object) EmitEvent(sender ObjectRefInternal, eventID int64, args codec.Raw) (bool, error) {
 	var prevent = false
 
-	if events, ok := o.registerdEvents[eventID]; ok {
-		for _, e := range events {
-			r, err := e.Call(sender, args)
-			if err != nil {
-				return false, err
-			}
-			if r {
-				prevent = true
-			}
+	handlers := o.EventHandlers(eventID)
+	for _, e := range handlers {
+		r, err := e.Call(sender, args)
+		if err != nil {
+			return false, err
+		}
+		if r {
+			prevent = true
+		}
+	}
+	for _, e := range handlers {
+		if ob, ok := e.(ResultObserver); ok {
+			ob.Observe(sender, args, prevent)
 		}
 	}
 	return prevent, nil
 }
 
-func (o *Object) Destroy() {
-	log.Printf("destroy: %d", o.Id)
-}
-
+// Destroyed marks o as destroyed, and removes it from the registry.
+// The dependents of o are destroyed too.
 func (o *Object) Destroyed() {
+	if !atomic.CompareAndSwapInt32(&o.state.destroyed, 0, 1) {
+		return
+	}
 	log.Printf("destroyed: %d", o.Id)
 	lock.Lock()
-	defer lock.Unlock()
-	if tm, ok := objects[o.ObjType]; ok {
+	if tm, ok := objects[o.ObjType]; ok && tm[o.Id] != nil && tm[o.Id].Base().state == o.state {
 		delete(tm, o.Id)
 		if len(tm) == 0 {
 			delete(objects, o.ObjType)
 		}
 	}
+	lock.Unlock()
+
+	o.state.lock.Lock()
+	deps := o.state.dependents
+	o.state.dependents = nil
+	o.state.lock.Unlock()
+	for _, d := range deps {
+		d.Destroyed()
+	}
+}
+
+// AddDependent makes d destroyed together with o, such as the page of a window.
+// d is destroyed at once if o has been destroyed.
+func (o *Object) AddDependent(d ObjectRefInternal) {
+	o.state.lock.Lock()
+	if o.IsDestroyed() {
+		o.state.lock.Unlock()
+		d.Destroyed()
+		return
+	}
+	for _, e := range o.state.dependents {
+		if e == d {
+			o.state.lock.Unlock()
+			return
+		}
+	}
+	o.state.dependents = append(o.state.dependents, d)
+	o.state.lock.Unlock()
+}
+
+// IsDestroyed reports whether o has been destroyed.
+func (o *Object) IsDestroyed() bool {
+	return atomic.LoadInt32(&o.state.destroyed) != 0
+}
+
+// Alive returns an apierror.ErrObjectDestroyed error about method if o has been destroyed.
+func (o *Object) Alive(method string) error {
+	if o.IsDestroyed() {
+		return apierror.New(apierror.CodeObjectDestroyed, o.ObjType, o.Id, method, "")
+	}
+	return nil
 }
 
 type objForJSON struct {
@@ -122,54 +223,81 @@ func (o *Object) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&oj)
 }
 
-func (o *Object) UnmashalJSON(data []byte) error {
-	var oj objForJSON
-	err := json.Unmarshal(data, &oj)
-	if err != nil {
+// UnmarshalJSON sets *o to the registered object referred by data.
+//
+// The result is a copy of the Object embedded in the wrapper, which shares its state:
+// destroying either destroys both. Use Ref to get the wrapper itself.
+func (o *Object) UnmarshalJSON(data []byte) error {
+	var r Ref
+	if err := json.Unmarshal(data, &r); err != nil {
 		return err
 	}
-	obj := GetObject(oj.Type, oj.ID)
-	if obj == nil {
-		return errors.New("invalid object id")
+	if r.Object == nil {
+		return fmt.Errorf("unknown object: %d/%d", r.Type, r.ID)
 	}
-	*o = *obj.(*Object)
+	*o = *r.Object.Base()
 	return nil
 }
 
-func (o *Object) AddRegisterdCallback(eventID int64, callback CallbackInterface) int {
-	cl := []CallbackInterface{callback}
+// Ref is a reference to an object in a framework result, which is encoded as {"type":1,"id":2}.
+// A null reference decodes into the zero Ref.
+type Ref struct {
+	Type   obj.ObjectType
+	ID     int64
+	Object ObjectRefInternal // registered wrapper of the object, or nil if it is not registered
+}
 
-	var ret int
-	if el, ok := o.registerdEvents[eventID]; ok {
-		ret = len(el)
-		o.registerdEvents[eventID] = append(cl, el...)
-	} else {
-		o.registerdEvents[eventID] = cl
+// UnmarshalJSON decodes the reference, and resolves the registered wrapper.
+func (r *Ref) UnmarshalJSON(data []byte) error {
+	var oj *objForJSON
+	if err := json.Unmarshal(data, &oj); err != nil {
+		return err
+	}
+	*r = Ref{}
+	if oj != nil {
+		r.Type, r.ID = oj.Type, oj.ID
+		r.Object = GetObject(oj.Type, oj.ID)
 	}
-	return ret
+	return nil
 }
 
-func (o *Object) DelRegisterdCallback(eventID int64, no int) bool {
-	ret := false
-	if el, ok := o.registerdEvents[eventID]; ok {
-		if no == 0 {
-			el = nil
-		} else {
-			el = append(el[:no], el[no+1:]...)
-		}
-		if len(el) > 0 {
-			o.registerdEvents[eventID] = el
-		} else {
-			delete(o.registerdEvents, eventID)
-			ret = true
-		}
+// MarshalJSON encodes the reference.
+func (r Ref) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&objForJSON{Type: r.Type, ID: r.ID})
+}
+
+// MarshalMsgpack encodes o in the same form as MarshalJSON.
+func (o *Object) MarshalMsgpack() ([]byte, error) {
+	b, err := o.MarshalJSON()
+	if err != nil {
+		return nil, err
 	}
-	return ret
+	return codec.MsgPackFromJSON(b)
 }
 
-func (o *Object) FindCallback(eventID int64) []CallbackInterface {
-	if c, ok := o.registerdEvents[eventID]; ok {
-		return c
+// UnmarshalMsgpack is UnmarshalJSON for MessagePack.
+func (o *Object) UnmarshalMsgpack(data []byte) error {
+	b, err := codec.JSONFromMsgPack(data)
+	if err != nil {
+		return err
 	}
-	return nil
+	return o.UnmarshalJSON(b)
+}
+
+// UnmarshalMsgpack is UnmarshalJSON for MessagePack.
+func (r *Ref) UnmarshalMsgpack(data []byte) error {
+	b, err := codec.JSONFromMsgPack(data)
+	if err != nil {
+		return err
+	}
+	return r.UnmarshalJSON(b)
+}
+
+// MarshalMsgpack encodes the reference.
+func (r Ref) MarshalMsgpack() ([]byte, error) {
+	b, err := r.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return codec.MsgPackFromJSON(b)
 }
@@ -0,0 +1,46 @@
+// Package paths keeps the application paths overridden by app.SetPath,
+// which are also needed to start the framework.
+package paths
+
+import "sync"
+
+// UserData is the name of the directory of the user data, where the browser profile goes.
+const UserData = "userData"
+
+var (
+	lock      sync.RWMutex
+	overrides = make(map[string]string)
+)
+
+// Set overrides the path of name.
+func Set(name string, path string) {
+	lock.Lock()
+	defer lock.Unlock()
+	overrides[name] = path
+}
+
+// Delete removes the override of name.
+func Delete(name string) {
+	lock.Lock()
+	defer lock.Unlock()
+	delete(overrides, name)
+}
+
+// Override returns the path of name set by Set.
+func Override(name string) (string, bool) {
+	lock.RLock()
+	defer lock.RUnlock()
+	p, ok := overrides[name]
+	return p, ok
+}
+
+// Overrides returns all the paths set by Set.
+func Overrides() map[string]string {
+	lock.RLock()
+	defer lock.RUnlock()
+	r := make(map[string]string, len(overrides))
+	for name, p := range overrides {
+		r[name] = p
+	}
+	return r
+}
@@ -2,10 +2,12 @@ package menu
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
+	"github.com/go-meson/meson/apierror"
 	"github.com/go-meson/meson/internal/binding"
+	"github.com/go-meson/meson/internal/codec"
 	"github.com/go-meson/meson/internal/command"
 	"github.com/go-meson/meson/internal/event"
 	"github.com/go-meson/meson/internal/object"
@@ -14,6 +16,7 @@ import (
 	"github.com/go-meson/meson/window"
 	"log"
 	"runtime"
+	"sync"
 	"text/template"
 )
 
@@ -149,11 +152,15 @@ func newItemTemplateWrapper(mi *ItemTemplate) *menuItemTemplateWrapper {
 	}
 }
 
+func invalidTemplate(msg string) error {
+	return apierror.New(apierror.CodeInvalidArgument, binding.ObjMenu, binding.ObjStaticID, "", msg)
+}
+
 func (mi *ItemTemplate) fixMenuType() error {
 	if len(mi.SubMenu) > 0 {
 		mi.Type = binding.MenuTypeSubmenu
 	} else if mi.Type == MenuTypeSubmenu {
-		return fmt.Errorf("Template type is MenuTypeSubmenu, but not have SubMenu.")
+		return invalidTemplate("Template type is MenuTypeSubmenu, but not have SubMenu.")
 	}
 	return nil
 }
@@ -169,7 +176,7 @@ func (mi *ItemTemplate) applyRole() error {
 		r, ok = menuRoleMap[mi.Role]
 	}
 	if !ok {
-		return fmt.Errorf("unrecognized role %q", mi.Role)
+		return invalidTemplate(fmt.Sprintf("unrecognized role %q", mi.Role))
 	}
 	if mi.Label == "" {
 		mi.Label = r.Label
@@ -199,16 +206,15 @@ type menuItemClickItem struct {
 	mi *ItemTemplate
 }
 
-func (p menuItemClickItem) Call(o obj.ObjectRef, arg json.RawMessage) (bool, error) {
+func (p menuItemClickItem) Call(o obj.ObjectRef, arg codec.Raw) (bool, error) {
 	log.Printf("menuItemClickItem::Call : %#v\n", arg)
 	args := struct {
 		FocusID int64 `json:"focusID"`
 	}{}
-	if err := json.Unmarshal(arg, &args); err != nil {
+	if err := codec.Decode(arg, &args); err != nil {
 		return false, err
 	}
-	win := object.GetObject(binding.ObjWindow, args.FocusID).(*window.Window)
-	p.mi.Click(p.mi, win)
+	p.mi.Click(p.mi, window.FromID(args.FocusID))
 	return false, nil
 }
 
@@ -225,7 +231,7 @@ func (m *Template) collectMenuID(idMap map[int]*ItemTemplate) error {
 			continue
 		}
 		if _, ok := idMap[mi.ID]; ok {
-			return fmt.Errorf("Menu ID conflict: %d", mi.ID)
+			return invalidTemplate(fmt.Sprintf("Menu ID conflict: %d", mi.ID))
 		}
 		idMap[mi.ID] = mi
 	}
@@ -257,6 +263,8 @@ func (m *Template) fillMenuID(idMap map[int]*ItemTemplate) {
 
 type Menu struct {
 	object.Object
+	lock     sync.Mutex
+	subMenus []*Menu // made by LoadTemplate
 }
 
 func newMenu(id int64) *Menu {
@@ -266,31 +274,49 @@ func newMenu(id int64) *Menu {
 }
 
 func NewWithTemplate(template Template) (*Menu, error) {
-	if !command.APIReady {
-		return nil, errors.New("meson api is not ready yet")
-	}
 	cmd := command.MakeCreateCommand(binding.ObjMenu)
+	if err := command.CheckReady(cmd.Type, cmd.Method); err != nil {
+		return nil, err
+	}
 
 	resp, err := command.SendMessage(&cmd)
 	if err != nil {
 		return nil, err
 	}
 	var cr command.CreateRespResult
-	err = json.Unmarshal(resp, &cr)
+	err = codec.Decode(resp, &cr)
 	if err != nil {
 		return nil, err
 	}
 
 	menu := newMenu(cr.ID)
 	if err := menu.LoadTemplate(template); err != nil {
-		//TODO: destory object...
+		menu.Destroy()
 		return nil, err
 	}
 
 	return menu, nil
 }
 
+// Destroy destroys the menu and its submenus.
+// Methods of a destroyed menu return an apierror.ErrObjectDestroyed error.
+func (m *Menu) Destroy() error {
+	m.lock.Lock()
+	subMenus := m.subMenus
+	m.subMenus = nil
+	m.lock.Unlock()
+	for _, sm := range subMenus {
+		if err := sm.Destroy(); err != nil && !errors.Is(err, apierror.ErrObjectDestroyed) {
+			log.Printf("destroy submenu %d fail: %v", sm.Id, err)
+		}
+	}
+	return command.Destroy(&m.Object)
+}
+
 func (m *Menu) LoadTemplate(template Template) error {
+	if err := m.Alive("loadTemplate"); err != nil {
+		return err
+	}
 	idMap := make(map[int]*ItemTemplate)
 	if err := template.collectMenuID(idMap); err != nil {
 		return err
@@ -312,6 +338,11 @@ func (m *Menu) LoadTemplate(template Template) error {
 				return err
 			}
 			mi.subMenuID = sm.Id
+			m.lock.Lock()
+			m.subMenus = append(m.subMenus, sm)
+			m.lock.Unlock()
+			// the submenu is gone with the menu, even if the framework destroys the menu.
+			m.AddDependent(sm)
 		}
 	}
 
@@ -326,15 +357,14 @@ func (m *Menu) LoadTemplate(template Template) error {
 		eventID := tempEvents[idx].EventID
 		eventName := tempEvents[idx].EventName
 		mi.eventName = eventName
-		m.AddRegisterdCallback(eventID, menuItemClickItem{mi: mi})
+		event.AddTemporaryCallback(&m.Object, eventID, menuItemClickItem{mi: mi})
 	}
 
 	items := make([]interface{}, len(template))
 	for i, t := range template {
 		items[i] = newItemTemplateWrapper(&t)
 	}
-	cmd := command.MakeCallCommand(m.ObjType, m.Id, "loadTemplate", items...)
-	_, err = command.SendMessage(&cmd)
+	_, err = command.Call(context.Background(), &m.Object, "loadTemplate", items...)
 	if err != nil {
 		return err
 	}
@@ -345,7 +375,10 @@ func (m *Menu) LoadTemplate(template Template) error {
 func SetApplicationMenu(menu *Menu) error {
 	if runtime.GOOS != "darwin" {
 		//TODO: linux/windowsでの動作確認
-		return errors.New("Current platform is not supported this method currently.")
+		return apierror.New(apierror.CodeUnsupported, binding.ObjMenu, binding.ObjStaticID, "setApplicationMenu", "Current platform is not supported this method currently.")
+	}
+	if err := menu.Alive("setApplicationMenu"); err != nil {
+		return err
 	}
 	cmd := command.MakeCallCommand(binding.ObjMenu, binding.ObjStaticID, "setApplicationMenu", menu)
 	_, err := command.SendMessage(&cmd)
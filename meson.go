@@ -1,30 +1,284 @@
 package meson
 
+import "fmt"
 import "runtime"
 import "time"
 import "log"
+import "os"
+import "strings"
+import "sync"
+import "github.com/go-meson/meson/apierror"
 import "github.com/go-meson/meson/internal/binding"
 import "github.com/go-meson/meson/internal/command"
+import "github.com/go-meson/meson/internal/paths"
+import "github.com/go-meson/meson/transport"
 
 func init() {
 	runtime.LockOSThread()
+	if path := os.Getenv("MESON_RECORD"); path != "" {
+		if err := StartRecording(path); err != nil {
+			log.Printf("start recording fail: %v", err)
+		}
+	}
 }
 
 // MainLoop start meson application main loop. It returns an exit code to pass to App.Exit.
+//
+// It exits the process when the framework fails to start. Use Run to handle the failures.
 func MainLoop(args []string, onInit func()) int {
-	err := binding.LoadBinding()
+	err := Run(Options{
+		Args: args,
+		OnInit: func() error {
+			onInit()
+			return nil
+		},
+	})
+	if e, ok := err.(*ExitError); ok {
+		return e.Code
+	}
 	if err != nil {
 		log.Fatal(err)
-		return -1
+	}
+	return 0
+}
+
+// DefaultReadyTimeout is how long Run waits for the framework to be ready by default.
+const DefaultReadyTimeout = 3 * time.Second
+
+// Options configures Run.
+type Options struct {
+	// Args is the command line passed to the framework. os.Args is used if nil.
+	Args []string
+	// ReadyTimeout is how long to wait for the framework to be ready.
+	// DefaultReadyTimeout is used if zero, and it waits forever if negative.
+	// The negotiation of the wire format and the set up of the API after that have their own short timeouts,
+	// so Run always goes on to OnInit or returns.
+	ReadyTimeout time.Duration
+	// FrameworkPath overrides the path of the framework, which is resolved from the build otherwise.
+	FrameworkPath string
+	// Switches are extra Chromium switches such as "--disable-gpu" or "remote-debugging-port=9222".
+	// "--" is prepended to a switch without it.
+	// "--user-data-dir" is added when app.SetPath sets the user data directory.
+	Switches []string
+	// OnInit is called once the framework is ready. When it returns an error,
+	// the application exits and Run returns the error.
+	OnInit func() error
+}
+
+// ExitError is returned by Run when the application exits with a non-zero code.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("meson: application exits with code %d", e.Code)
+}
+
+// Run loads the framework and runs the application main loop until the application exits.
+// It must be called from the main goroutine.
+//
+// Failures are returned instead of exiting the process. When OnInit fails, Run asks the framework to exit
+// and returns the failure after the main loop ends. When the framework is not ready in time, its main loop
+// is aborted by MesonApiAbort and the failure is returned. A framework which does not export MesonApiAbort
+// can not be aborted, so Run may not return then; the failure is logged, and returned if the loop ever ends.
+func Run(opts Options) error {
+	var err error
+	if opts.FrameworkPath != "" {
+		err = binding.LoadBindingFrom(opts.FrameworkPath)
+	} else {
+		err = binding.LoadBinding()
+	}
+	if err != nil {
+		return err
+	}
+
+	failed := make(chan error, 1)
+	var once sync.Once
+	fail := func(err error, ready bool) {
+		once.Do(func() {
+			failed <- err
+			if !ready {
+				// the framework serves no message yet, so "exit" would never be processed.
+				if !binding.AbortMainLoop(1) {
+					log.Printf("meson: %v; the main loop can not be aborted", err)
+				}
+				return
+			}
+			cmd := command.MakeCallCommand(binding.ObjApp, binding.ObjStaticID, "exit", 1)
+			command.PostMessage(&cmd)
+		})
 	}
 	go func() {
+		var timeout <-chan time.Time
+		switch {
+		case opts.ReadyTimeout == 0:
+			timeout = time.After(DefaultReadyTimeout)
+		case opts.ReadyTimeout > 0:
+			timeout = time.After(opts.ReadyTimeout)
+		}
 		select {
-		case <-binding.ReadyChannel:
-			command.APIReady = true
-			onInit()
-		case <-time.After(3 * time.Second):
-			log.Fatal("Waited for 3 seconds without ready signal")
+		case <-command.TransportReady():
+		case <-timeout:
+			fail(apierror.New(apierror.CodeTimeout, binding.ObjApp, binding.ObjStaticID, "", "framework is not ready in time"), false)
+			return
+		}
+		<-command.Ready()
+		if opts.OnInit == nil {
+			return
+		}
+		if err := runInit(opts.OnInit); err != nil {
+			fail(err, true)
+		}
+	}()
+
+	code := binding.RunMesonMainLoop(makeArgs(opts))
+	command.SetState(command.StateExited)
+	select {
+	case err := <-failed:
+		return err
+	default:
+	}
+	if code != 0 {
+		return &ExitError{Code: code}
+	}
+	return nil
+}
+
+// runInit calls onInit, and returns a panic in it as an error.
+func runInit(onInit func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("meson: panic in OnInit: %v", r)
 		}
 	}()
-	return binding.RunMesonMainLoop(args)
+	return onInit()
+}
+
+func makeArgs(opts Options) []string {
+	args := opts.Args
+	if args == nil {
+		args = os.Args
+	}
+	args = append([]string{}, args...)
+	if dir, ok := paths.Override(paths.UserData); ok {
+		args = append(args, "--user-data-dir="+dir)
+	}
+	for _, s := range opts.Switches {
+		if !strings.HasPrefix(s, "-") {
+			s = "--" + s
+		}
+		args = append(args, s)
+	}
+	return args
+}
+
+// SetTransport replaces the transport between Go and the meson framework.
+//
+// It is intended for tests which drive the API through transport.Loopback instead of the framework.
+func SetTransport(t transport.Transport) {
+	command.SetTransport(t)
+}
+
+// Ready returns a channel which is closed once the meson API is ready to use.
+func Ready() <-chan struct{} {
+	return command.Ready()
+}
+
+// Batch calls fn, and ships the commands posted while fn runs to the framework in one frame.
+// The framework applies them in order.
+func Batch(fn func()) error {
+	return command.Batch(func(*command.Batcher) {
+		fn()
+	})
+}
+
+// StartRecording records the messages exchanged with the framework into the file of path as JSON lines,
+// until StopRecording is called. Setting MESON_RECORD environment variable starts recording on startup.
+//
+// The recorded session can be played back by Replayer.
+func StartRecording(path string) error {
+	r, err := command.CreateRecorder(path)
+	if err != nil {
+		return err
+	}
+	command.SetRecorder(r)
+	return nil
+}
+
+// StopRecording stops the recording started by StartRecording.
+func StopRecording() error {
+	r := command.CurrentRecorder()
+	if r == nil {
+		return nil
+	}
+	command.SetRecorder(nil)
+	return r.Close()
+}
+
+// Replayer plays a recorded session back as the framework. Pass it to SetTransport,
+// then call Run to play the session against the Go code.
+type Replayer = command.Replayer
+
+// OpenReplayer creates a Replayer of the session recorded in the file of path.
+func OpenReplayer(path string) (*Replayer, error) {
+	return command.OpenReplayer(path)
+}
+
+// Fault is a message from the framework which Go can not handle, such as an event
+// which arrives after its object is destroyed.
+type Fault = command.Fault
+
+// FaultKind is the kind of a Fault.
+type FaultKind = command.FaultKind
+
+// Kinds of Fault.
+const (
+	FaultDecode          = command.FaultDecode
+	FaultUnknownResponse = command.FaultUnknownResponse
+	FaultUnknownObject   = command.FaultUnknownObject
+	FaultInvalidAction   = command.FaultInvalidAction
+	FaultEventPayload    = command.FaultEventPayload
+	FaultHandlerPanic    = command.FaultHandlerPanic
+)
+
+// FaultAction is what to do with a Fault.
+type FaultAction = command.FaultAction
+
+// Actions for a Fault.
+const (
+	FaultDrop  = command.FaultDrop
+	FaultLog   = command.FaultLog
+	FaultAbort = command.FaultAbort
+)
+
+// SetFaultHandler sets the handler which decides what to do with protocol faults.
+//
+// By default, events on unknown objects, undecodable event payloads and panics in event handlers
+// are logged, and the other faults abort the process.
+// A nil handler restores the default.
+func SetFaultHandler(handler func(f *Fault) FaultAction) {
+	command.SetFaultHandler(handler)
+}
+
+// DispatchPolicy decides how events which need no reply are delivered to the handlers.
+type DispatchPolicy = command.DispatchPolicy
+
+// Dispatch policies. DispatchSerialPerObject is the default.
+const (
+	DispatchSerialPerObject = command.DispatchSerialPerObject
+	DispatchSerial          = command.DispatchSerial
+	DispatchPool            = command.DispatchPool
+)
+
+// SetDispatchPolicy changes how events are delivered to the handlers.
+// workers is the number of workers for DispatchPool; the number of CPUs if it is not positive.
+//
+// Events which the framework waits a reply for, such as preventable ones, are always delivered at once.
+func SetDispatchPolicy(policy DispatchPolicy, workers int) {
+	command.SetDispatchPolicy(policy, workers)
+}
+
+// EventQueueDepth returns the number of events waiting to be delivered to the handlers.
+func EventQueueDepth() int {
+	return command.QueueDepth()
 }
@@ -152,7 +152,7 @@ func downloadFramework(version string) (string, error) {
 // FetchFramework() download meson-framework from github release and cache your machine.
 func FetchFramework(version string) error {
 	rootPath := GetFrameworkRootPath(version)
-	if stat, err := os.Stat(rootPath); err == nil && stat.IsDir() {
+	if _, err := os.Stat(rootPath); err == nil {
 		return nil
 	}
 	basePath := FrameworkBasePath(version)
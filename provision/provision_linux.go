@@ -0,0 +1,37 @@
+package provision
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// FrameworkLibraryName is the file name of the meson shared library.
+const FrameworkLibraryName = "libmeson.so"
+
+func GetFrameworkRootPath(version string) string {
+	return GetFrameworkPathFromRootPath(FrameworkBasePath(version))
+}
+
+func GetFrameworkPathFromRootPath(rootPath string) string {
+	return filepath.Join(rootPath, FrameworkLibraryName)
+}
+
+// GetFrameworkPath returns the meson shared library path.
+//
+// A library placed next to the executable takes precedence over the provisioned one.
+func GetFrameworkPath(version string) (string, error) {
+	if exe, err := os.Executable(); err == nil {
+		libPath := GetFrameworkPathFromRootPath(filepath.Dir(exe))
+		if stat, err := os.Stat(libPath); err == nil && !stat.IsDir() {
+			return libPath, nil
+		}
+	}
+	if err := FetchFramework(version); err != nil {
+		return "", err
+	}
+	libPath := GetFrameworkRootPath(version)
+	if _, err := os.Stat(libPath); err != nil {
+		return "", err
+	}
+	return libPath, nil
+}
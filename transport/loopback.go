@@ -0,0 +1,90 @@
+package transport
+
+import (
+	"errors"
+	"sync"
+)
+
+// Loopback is an in-memory Transport. The test code plays the framework side:
+// it reads requests from Requests() and delivers responses and events by Post().
+//
+// Requests must be served before SetReady, since the Go side negotiates the protocol
+// as soon as the transport is ready. Any reply to the negotiation keeps the defaults.
+type Loopback struct {
+	requests  chan []byte
+	ready     chan struct{}
+	readyOnce sync.Once
+	lock      sync.RWMutex
+	handler   ReceiveHandler
+	binary    bool
+}
+
+// NewLoopback create a new Loopback transport.
+func NewLoopback() *Loopback {
+	return &Loopback{
+		requests: make(chan []byte),
+		ready:    make(chan struct{}),
+	}
+}
+
+// Send implements Transport. It blocks until the request is read from Requests().
+func (l *Loopback) Send(msg []byte) error {
+	l.requests <- msg
+	return nil
+}
+
+// SetReceiveHandler implements Transport.
+func (l *Loopback) SetReceiveHandler(handler ReceiveHandler) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.handler = handler
+}
+
+// Ready implements Transport.
+func (l *Loopback) Ready() <-chan struct{} {
+	return l.ready
+}
+
+// Reentrant implements Reentrant. The framework side of Loopback runs on its own goroutines.
+func (l *Loopback) Reentrant() bool {
+	return true
+}
+
+// Binary implements Binary.
+func (l *Loopback) Binary() bool {
+	return l.binary
+}
+
+// Negotiable implements Negotiable. The test code answers the negotiation.
+func (l *Loopback) Negotiable() bool {
+	return true
+}
+
+// EnableBinary makes the loopback offer a binary codec to the Go side.
+// It must be called before SetReady.
+func (l *Loopback) EnableBinary() {
+	l.binary = true
+}
+
+// SetReady signals that the framework side is ready.
+func (l *Loopback) SetReady() {
+	l.readyOnce.Do(func() {
+		close(l.ready)
+	})
+}
+
+// Requests returns the channel of messages sent by Go code.
+func (l *Loopback) Requests() <-chan []byte {
+	return l.requests
+}
+
+// Post delivers msg to the registered receive handler, as the framework does.
+func (l *Loopback) Post(id int64, msg []byte, needReply bool) ([]byte, error) {
+	l.lock.RLock()
+	handler := l.handler
+	l.lock.RUnlock()
+	if handler == nil {
+		return nil, errors.New("receive handler is not registered")
+	}
+	return handler(id, msg, needReply), nil
+}
@@ -0,0 +1,39 @@
+// Package transport carries encoded messages between meson and the framework.
+package transport
+
+// ReceiveHandler is called for each message delivered by the framework.
+// When needReply is true, the returned bytes are sent back as the reply.
+type ReceiveHandler func(id int64, msg []byte, needReply bool) []byte
+
+// Transport is the message channel between Go and the meson framework.
+type Transport interface {
+	// Send delivers a request message to the framework.
+	Send(msg []byte) error
+	// SetReceiveHandler registers the handler for messages from the framework.
+	SetReceiveHandler(handler ReceiveHandler)
+	// Ready returns a channel which is closed once the framework accepts requests.
+	Ready() <-chan struct{}
+}
+
+// Binary is implemented by transports which can carry any bytes, not only text.
+//
+// A binary codec is negotiated with the framework only over binary transports.
+type Binary interface {
+	Binary() bool
+}
+
+// Negotiable is implemented by transports whose framework answers the negotiation of the wire format.
+//
+// The codec and batch frames are negotiated only over negotiable transports;
+// the others keep JSON without batch frames.
+type Negotiable interface {
+	Negotiable() bool
+}
+
+// Reentrant is implemented by transports which can serve requests while a reply to the framework is pending.
+//
+// When the transport is reentrant, synchronous calls can be made from event handlers which need a reply.
+// Otherwise such calls fail, and the handlers must use asynchronous calls.
+type Reentrant interface {
+	Reentrant() bool
+}
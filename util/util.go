@@ -1,23 +1,9 @@
 package util
 
-/*
-#cgo darwin CFLAGS: -mmacosx-version-min=10.9
-#cgo darwin LDFLAGS: -mmacosx-version-min=10.9
-#cgo darwin LDFLAGS: -framework Foundation -framework AppKit
-
-#include <stdlib.h>
-
-extern char* mesonGetBundlePath(void);
-extern char* mesonGetSystemDirectoryPath(int);
-extern char* mesonGetApplicationName(void);
-*/
-import "C"
 import "path/filepath"
 import "os"
 import "strings"
 
-import "unsafe"
-
 // SystemDirectoryType specify the location of variety of directories by the GetSystemFolderPath() function.
 type SystemDirectoryType int
 
@@ -29,6 +15,22 @@ const (
 	DocumentDirectory
 	// DesktopDirectory is location of user's desktop directory
 	DesktopDirectory
+	// ConfigDirectory is location of user's configuration files
+	ConfigDirectory
+	// DataDirectory is location of user's application data files
+	DataDirectory
+	// DownloadsDirectory is location of user's downloads directory
+	DownloadsDirectory
+	// PicturesDirectory is location of user's pictures directory
+	PicturesDirectory
+	// MusicDirectory is location of user's music directory
+	MusicDirectory
+	// VideosDirectory is location of user's videos directory
+	VideosDirectory
+	// LogsDirectory is location of user's log files
+	LogsDirectory
+	// TempDirectory is location of temporary files
+	TempDirectory
 )
 
 var (
@@ -42,35 +44,7 @@ var (
 	ApplicationName = getApplicationName()
 )
 
-func getApplicationBundlePath() string {
-	cstr := C.mesonGetBundlePath()
-	if cstr == nil {
-		return ""
-	}
-	str := C.GoString(cstr)
-	C.free(unsafe.Pointer(cstr))
-	return str
-}
-
-// GetSystemDirectoryPath return the specified common directory
-func GetSystemDirectoryPath(dir SystemDirectoryType) string {
-	cdir := C.int(dir)
-	cstr := C.mesonGetSystemDirectoryPath(cdir)
-	if cstr == nil {
-		return ""
-	}
-	str := C.GoString(cstr)
-	C.free(unsafe.Pointer(cstr))
-	return str
-}
-
-func getApplicationName() string {
-	cstr := C.mesonGetApplicationName()
-	if cstr == nil {
-		base := filepath.Base(os.Args[0])
-		return strings.TrimSuffix(filepath.Base(base), filepath.Ext(base))
-	}
-	str := C.GoString(cstr)
-	C.free(unsafe.Pointer(cstr))
-	return str
+func getExecutableName() string {
+	base := filepath.Base(os.Args[0])
+	return strings.TrimSuffix(filepath.Base(base), filepath.Ext(base))
 }
@@ -1,8 +1,57 @@
 package util
 
+/*
+#cgo darwin CFLAGS: -mmacosx-version-min=10.9
+#cgo darwin LDFLAGS: -mmacosx-version-min=10.9
+#cgo darwin LDFLAGS: -framework Foundation -framework AppKit
+
+#include <stdlib.h>
+
+extern char* mesonGetBundlePath(void);
+extern char* mesonGetSystemDirectoryPath(int);
+extern char* mesonGetApplicationName(void);
+*/
+import "C"
 import "os"
 import "path/filepath"
 
+import "unsafe"
+
+func getApplicationBundlePath() string {
+	cstr := C.mesonGetBundlePath()
+	if cstr == nil {
+		return ""
+	}
+	str := C.GoString(cstr)
+	C.free(unsafe.Pointer(cstr))
+	return str
+}
+
+// GetSystemDirectoryPath return the specified common directory
+func GetSystemDirectoryPath(dir SystemDirectoryType) string {
+	if dir == TempDirectory {
+		return os.TempDir()
+	}
+	cdir := C.int(dir)
+	cstr := C.mesonGetSystemDirectoryPath(cdir)
+	if cstr == nil {
+		return ""
+	}
+	str := C.GoString(cstr)
+	C.free(unsafe.Pointer(cstr))
+	return str
+}
+
+func getApplicationName() string {
+	cstr := C.mesonGetApplicationName()
+	if cstr == nil {
+		return getExecutableName()
+	}
+	str := C.GoString(cstr)
+	C.free(unsafe.Pointer(cstr))
+	return str
+}
+
 func getApplicationAssetsPath(bundlePath string) string {
 	if bundlePath == "" {
 		//TODO: change os.Executable in Go1.8
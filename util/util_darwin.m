@@ -43,6 +43,7 @@ char *mesonGetSystemDirectoryPath(int type) {
   NSSearchPathDirectory pathType;
   NSSearchPathDomainMask domainType = NSUserDomainMask;
   BOOL isCreate = FALSE;
+  NSString *subPath = nil;
 
   switch (type) {
   case 1:
@@ -56,6 +57,27 @@ char *mesonGetSystemDirectoryPath(int type) {
   case 3:
     pathType = NSDesktopDirectory;
     break;
+  case 4:
+  case 5:
+    pathType = NSApplicationSupportDirectory;
+    break;
+  case 6:
+    pathType = NSDownloadsDirectory;
+    break;
+  case 7:
+    pathType = NSPicturesDirectory;
+    break;
+  case 8:
+    pathType = NSMusicDirectory;
+    break;
+  case 9:
+    pathType = NSMoviesDirectory;
+    break;
+  case 10:
+    // ~/Library/Logs
+    pathType = NSLibraryDirectory;
+    subPath = @"Logs";
+    break;
   default:
     return NULL;
   }
@@ -68,6 +90,9 @@ char *mesonGetSystemDirectoryPath(int type) {
                    appropriateForURL:nil
                               create:TRUE
                                error:nil];
+    if (url && subPath) {
+      url = [url URLByAppendingPathComponent:subPath isDirectory:TRUE];
+    }
     if (url) {
       NSString *str = url.path;
       if (str) {
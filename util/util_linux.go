@@ -0,0 +1,131 @@
+package util
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// user directories of xdg-user-dirs, and the fallback relative to $HOME.
+var xdgUserDirectories = map[SystemDirectoryType]struct {
+	key      string
+	fallback string
+}{
+	DesktopDirectory:   {"XDG_DESKTOP_DIR", "Desktop"},
+	DocumentDirectory:  {"XDG_DOCUMENTS_DIR", "Documents"},
+	DownloadsDirectory: {"XDG_DOWNLOAD_DIR", "Downloads"},
+	PicturesDirectory:  {"XDG_PICTURES_DIR", "Pictures"},
+	MusicDirectory:     {"XDG_MUSIC_DIR", "Music"},
+	VideosDirectory:    {"XDG_VIDEOS_DIR", "Videos"},
+}
+
+func getApplicationBundlePath() string {
+	return ""
+}
+
+// GetSystemDirectoryPath return the specified common directory
+func GetSystemDirectoryPath(dir SystemDirectoryType) string {
+	switch dir {
+	case UserCacheDirectory:
+		return xdgBaseDirectory("XDG_CACHE_HOME", ".cache")
+	case ConfigDirectory:
+		return xdgBaseDirectory("XDG_CONFIG_HOME", ".config")
+	case DataDirectory:
+		return xdgBaseDirectory("XDG_DATA_HOME", filepath.Join(".local", "share"))
+	case LogsDirectory:
+		return xdgBaseDirectory("XDG_STATE_HOME", filepath.Join(".local", "state"))
+	case TempDirectory:
+		return os.TempDir()
+	}
+	if ud, ok := xdgUserDirectories[dir]; ok {
+		return xdgUserDirectory(ud.key, ud.fallback)
+	}
+	return ""
+}
+
+func homeDirectory() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return home
+}
+
+// xdgBaseDirectory resolves a directory of the XDG Base Directory Specification.
+// Relative paths in the environment variable are ignored as the specification requires.
+func xdgBaseDirectory(env string, fallback string) string {
+	if p := os.Getenv(env); p != "" && filepath.IsAbs(p) {
+		return p
+	}
+	home := homeDirectory()
+	if home == "" {
+		return ""
+	}
+	return filepath.Join(home, fallback)
+}
+
+// xdgUserDirectory resolves a well known user directory from the environment or user-dirs.dirs.
+func xdgUserDirectory(key string, fallback string) string {
+	home := homeDirectory()
+	if p := os.Getenv(key); p != "" {
+		return expandUserDirectory(p, home)
+	}
+	f, err := os.Open(filepath.Join(GetSystemDirectoryPath(ConfigDirectory), "user-dirs.dirs"))
+	if err == nil {
+		defer f.Close()
+		if p, ok := parseUserDirs(bufio.NewScanner(f), key); ok {
+			return expandUserDirectory(p, home)
+		}
+	}
+	if home == "" {
+		return ""
+	}
+	return filepath.Join(home, fallback)
+}
+
+// parseUserDirs finds key in user-dirs.dirs, whose lines look like `XDG_DESKTOP_DIR="$HOME/Desktop"`.
+func parseUserDirs(s *bufio.Scanner, key string) (string, bool) {
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) != key {
+			continue
+		}
+		value := strings.TrimSpace(kv[1])
+		if v, err := strconv.Unquote(value); err == nil {
+			value = v
+		}
+		if value == "" {
+			return "", false
+		}
+		return value, true
+	}
+	return "", false
+}
+
+func expandUserDirectory(p string, home string) string {
+	switch {
+	case p == "$HOME":
+		return home
+	case strings.HasPrefix(p, "$HOME/"):
+		return filepath.Join(home, p[len("$HOME/"):])
+	}
+	return filepath.Clean(p)
+}
+
+func getApplicationName() string {
+	return getExecutableName()
+}
+
+func getApplicationAssetsPath(bundlePath string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		panic(err)
+	}
+	return filepath.Join(wd, "assets")
+}
@@ -0,0 +1,48 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSystemDirectoryXDG(t *testing.T) {
+	home, err := ioutil.TempDir("", "meson-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	config := filepath.Join(home, "conf")
+	if err := os.MkdirAll(config, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	userDirs := "# comment\nXDG_DESKTOP_DIR=\"$HOME/Schreibtisch\"\nXDG_MUSIC_DIR=\"/srv/music\"\n"
+	if err := ioutil.WriteFile(filepath.Join(config, "user-dirs.dirs"), []byte(userDirs), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", config)
+	t.Setenv("XDG_CACHE_HOME", "relative/cache")
+	t.Setenv("XDG_DATA_HOME", "/data")
+	t.Setenv("XDG_PICTURES_DIR", "$HOME/Bilder")
+
+	tests := []struct {
+		dir  SystemDirectoryType
+		want string
+	}{
+		{UserCacheDirectory, filepath.Join(home, ".cache")},
+		{ConfigDirectory, config},
+		{DataDirectory, "/data"},
+		{DesktopDirectory, filepath.Join(home, "Schreibtisch")},
+		{MusicDirectory, "/srv/music"},
+		{PicturesDirectory, filepath.Join(home, "Bilder")},
+		{DownloadsDirectory, filepath.Join(home, "Downloads")},
+		{TempDirectory, os.TempDir()},
+	}
+	for _, tt := range tests {
+		if got := GetSystemDirectoryPath(tt.dir); got != tt.want {
+			t.Errorf("GetSystemDirectoryPath(%d) = %q, want %q", tt.dir, got, tt.want)
+		}
+	}
+}
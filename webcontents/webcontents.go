@@ -0,0 +1,205 @@
+// Package webcontents renders and controls the page in a window.
+package webcontents
+
+import (
+	"context"
+
+	"github.com/go-meson/meson/internal/binding"
+	"github.com/go-meson/meson/internal/codec"
+	"github.com/go-meson/meson/internal/command"
+	"github.com/go-meson/meson/internal/object"
+)
+
+// WebContents is the page in a window. Get it by Window.WebContents.
+//
+// It is destroyed together with its window.
+type WebContents struct {
+	object.Object
+}
+
+// LoadURLOptions is optional parameter for LoadURLWithOptions.
+type LoadURLOptions struct {
+	HTTPReferer  string `json:"httpReferrer"` // A HTTP Referrer url.
+	UserAgent    string `json:"userAgent"`    // A user agent originating the request.
+	ExtraHeaders string `json:"extraHeaders"` // Extra headers separated by “\n”
+}
+
+// FromID returns the live web contents of id, or nil if there is no such web contents.
+func FromID(id int64) *WebContents {
+	wc, _ := object.GetObject(binding.ObjWebContents, id).(*WebContents)
+	return wc
+}
+
+// Attach returns the web contents of id, making its wrapper if it is unknown to Go yet.
+func Attach(id int64) *WebContents {
+	o := object.LoadOrAddObject(binding.ObjWebContents, id, func() object.ObjectRefInternal {
+		return &WebContents{Object: object.NewObject(id, binding.ObjWebContents)}
+	})
+	return o.(*WebContents)
+}
+
+//------------------------------------------------------------------------
+// Navigation
+
+// LoadURL loads url in the page.
+func (wc *WebContents) LoadURL(url string) error {
+	return command.Post(&wc.Object, "loadURL", url)
+}
+
+// LoadURLWithOptions loads url in the page with opt.
+func (wc *WebContents) LoadURLWithOptions(url string, opt *LoadURLOptions) error {
+	return command.Post(&wc.Object, "loadURL", url, opt)
+}
+
+// Reload reloads the page.
+func (wc *WebContents) Reload() error {
+	return command.Post(&wc.Object, "reload")
+}
+
+// Stop stops loading the page.
+func (wc *WebContents) Stop() error {
+	return command.Post(&wc.Object, "stop")
+}
+
+// GoBack goes back in the history of the page.
+func (wc *WebContents) GoBack() error {
+	return command.Post(&wc.Object, "goBack")
+}
+
+// GoForward goes forward in the history of the page.
+func (wc *WebContents) GoForward() error {
+	return command.Post(&wc.Object, "goForward")
+}
+
+// CanGoBack reports whether the page can go back.
+func (wc *WebContents) CanGoBack() (bool, error) {
+	return wc.CanGoBackContext(context.Background())
+}
+
+// CanGoBackContext is same as CanGoBack, but gives up waiting the framework when ctx is done.
+func (wc *WebContents) CanGoBackContext(ctx context.Context) (bool, error) {
+	var b bool
+	err := wc.call(ctx, "canGoBack", &b)
+	return b, err
+}
+
+// CanGoForward reports whether the page can go forward.
+func (wc *WebContents) CanGoForward() (bool, error) {
+	return wc.CanGoForwardContext(context.Background())
+}
+
+// CanGoForwardContext is same as CanGoForward, but gives up waiting the framework when ctx is done.
+func (wc *WebContents) CanGoForwardContext(ctx context.Context) (bool, error) {
+	var b bool
+	err := wc.call(ctx, "canGoForward", &b)
+	return b, err
+}
+
+// URL returns the URL of the page.
+func (wc *WebContents) URL() (string, error) {
+	return wc.URLContext(context.Background())
+}
+
+// URLContext is same as URL, but gives up waiting the framework when ctx is done.
+func (wc *WebContents) URLContext(ctx context.Context) (string, error) {
+	var url string
+	err := wc.call(ctx, "getURL", &url)
+	return url, err
+}
+
+// Title returns the title of the page.
+func (wc *WebContents) Title() (string, error) {
+	return wc.TitleContext(context.Background())
+}
+
+// TitleContext is same as Title, but gives up waiting the framework when ctx is done.
+func (wc *WebContents) TitleContext(ctx context.Context) (string, error) {
+	var title string
+	err := wc.call(ctx, "getTitle", &title)
+	return title, err
+}
+
+// IsLoading reports whether the page is loading.
+func (wc *WebContents) IsLoading() (bool, error) {
+	return wc.IsLoadingContext(context.Background())
+}
+
+// IsLoadingContext is same as IsLoading, but gives up waiting the framework when ctx is done.
+func (wc *WebContents) IsLoadingContext(ctx context.Context) (bool, error) {
+	var b bool
+	err := wc.call(ctx, "isLoading", &b)
+	return b, err
+}
+
+//------------------------------------------------------------------------
+// Scripts
+
+// ExecuteJavaScript evaluates code in the page, and returns the result decoded into a Go value
+// as encoding/json does with whichever codec the transport uses: a number is float64,
+// an array is []interface{} and an object is map[string]interface{}.
+// A promise is resolved before it is returned.
+// An exception thrown by code is returned as an error.
+func (wc *WebContents) ExecuteJavaScript(ctx context.Context, code string) (interface{}, error) {
+	var v interface{}
+	if err := wc.ExecuteJavaScriptInto(ctx, code, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ExecuteJavaScriptInto is same as ExecuteJavaScript, but decodes the result into v.
+func (wc *WebContents) ExecuteJavaScriptInto(ctx context.Context, code string, v interface{}) error {
+	return wc.call(ctx, "executeJavaScript", v, code)
+}
+
+//------------------------------------------------------------------------
+// Edit commands
+
+// Undo undoes the last edit in the page.
+func (wc *WebContents) Undo() error {
+	return command.Post(&wc.Object, "undo")
+}
+
+// Redo redoes the last undone edit in the page.
+func (wc *WebContents) Redo() error {
+	return command.Post(&wc.Object, "redo")
+}
+
+// Cut cuts the selection in the page.
+func (wc *WebContents) Cut() error {
+	return command.Post(&wc.Object, "cut")
+}
+
+// Copy copies the selection in the page.
+func (wc *WebContents) Copy() error {
+	return command.Post(&wc.Object, "copy")
+}
+
+// Paste pastes the clipboard into the page.
+func (wc *WebContents) Paste() error {
+	return command.Post(&wc.Object, "paste")
+}
+
+// PasteAndMatchStyle pastes the clipboard into the page, matching the style of the surroundings.
+func (wc *WebContents) PasteAndMatchStyle() error {
+	return command.Post(&wc.Object, "pasteAndMatchStyle")
+}
+
+// Delete deletes the selection in the page.
+func (wc *WebContents) Delete() error {
+	return command.Post(&wc.Object, "delete")
+}
+
+// SelectAll selects the whole page.
+func (wc *WebContents) SelectAll() error {
+	return command.Post(&wc.Object, "selectAll")
+}
+
+// call calls method of the web contents, and decodes the result into v.
+func (wc *WebContents) call(ctx context.Context, method string, v interface{}, args ...interface{}) error {
+	r, err := command.Call(ctx, &wc.Object, method, args...)
+	if err != nil {
+		return err
+	}
+	return codec.Decode(r, v)
+}
@@ -0,0 +1,95 @@
+package window
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-meson/meson/apierror"
+	"github.com/go-meson/meson/internal/command"
+)
+
+// Bounds returns the position and the size of the window, including its frame.
+func (w *Window) Bounds() (Rect, error) {
+	return w.BoundsContext(context.Background())
+}
+
+// BoundsContext is same as Bounds, but gives up waiting the framework when ctx is done.
+func (w *Window) BoundsContext(ctx context.Context) (Rect, error) {
+	return w.callRect(ctx, "getBounds")
+}
+
+// SetBounds moves and resizes the window to r, including its frame.
+func (w *Window) SetBounds(r Rect) error {
+	if err := w.checkSize("setBounds", r.Width, r.Height); err != nil {
+		return err
+	}
+	return command.Post(&w.Object, "setBounds", r)
+}
+
+// ContentBounds returns the position and the size of the content area of the window.
+func (w *Window) ContentBounds() (Rect, error) {
+	return w.ContentBoundsContext(context.Background())
+}
+
+// ContentBoundsContext is same as ContentBounds, but gives up waiting the framework when ctx is done.
+func (w *Window) ContentBoundsContext(ctx context.Context) (Rect, error) {
+	return w.callRect(ctx, "getContentBounds")
+}
+
+// SetSize resizes the window to width and height in pixels, keeping its position.
+func (w *Window) SetSize(width int, height int) error {
+	if err := w.checkSize("setSize", width, height); err != nil {
+		return err
+	}
+	return command.Post(&w.Object, "setSize", width, height)
+}
+
+// SetPosition moves the window to left and top in pixels, keeping its size.
+func (w *Window) SetPosition(left int, top int) error {
+	return command.Post(&w.Object, "setPosition", left, top)
+}
+
+// Center moves the window to the center of the screen.
+func (w *Window) Center() error {
+	return command.Post(&w.Object, "center")
+}
+
+// SetMinimumSize limits the size of the window. Zero means no limit.
+func (w *Window) SetMinimumSize(width int, height int) error {
+	if err := w.checkSize("setMinimumSize", width, height); err != nil {
+		return err
+	}
+	return command.Post(&w.Object, "setMinimumSize", width, height)
+}
+
+// SetMaximumSize limits the size of the window. Zero means no limit.
+func (w *Window) SetMaximumSize(width int, height int) error {
+	if err := w.checkSize("setMaximumSize", width, height); err != nil {
+		return err
+	}
+	return command.Post(&w.Object, "setMaximumSize", width, height)
+}
+
+// SetAspectRatio keeps the width / height ratio of the content area when the window is resized.
+// Zero removes the constraint.
+func (w *Window) SetAspectRatio(ratio float64) error {
+	if ratio < 0 {
+		return apierror.New(apierror.CodeInvalidArgument, w.ObjType, w.Id, "setAspectRatio", fmt.Sprintf("negative aspect ratio %g", ratio))
+	}
+	return command.Post(&w.Object, "setAspectRatio", ratio)
+}
+
+func (w *Window) callRect(ctx context.Context, method string) (Rect, error) {
+	var r Rect
+	if err := w.call(ctx, method, &r); err != nil {
+		return Rect{}, err
+	}
+	return r, nil
+}
+
+func (w *Window) checkSize(method string, width int, height int) error {
+	if width < 0 || height < 0 {
+		return apierror.New(apierror.CodeInvalidArgument, w.ObjType, w.Id, method, fmt.Sprintf("negative size %dx%d", width, height))
+	}
+	return nil
+}
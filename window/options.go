@@ -0,0 +1,204 @@
+package window
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+
+	"github.com/go-meson/meson/apierror"
+	"github.com/go-meson/meson/internal/binding"
+	"github.com/go-meson/meson/util"
+)
+
+// WindowOptions contains options for creating windows.
+//
+// The zero value is an ordinary framed window. Options of *bool are true when they are nil;
+// use Bool to set them.
+type WindowOptions struct {
+	Title            string // String to display in title bar. util.ApplicationName when empty
+	IconPath         string
+	Shape            Rect  // Initial size and position of window. A zero size is the one of FramedWindowOptions
+	TitleBar         *bool // Whether the window title bar
+	Frame            *bool // Whether the window has a frame
+	Resizable        *bool // Whether the window border can be dragged to change its shape
+	CloseButton      *bool // Whether the window has a close button
+	MinButton        *bool // Whether the window has a miniaturize button
+	FullScreenButton *bool // Whether the window has a full screen button
+	Show             *bool // Whether the window is shown when it is created
+
+	BackgroundColor string // Color behind the page, as "#RGB", "#RRGGBB" or "#AARRGGBB"
+	Transparent     bool   // Whether the window is transparent. It requires no frame
+	AlwaysOnTop     bool   // Whether the window stays above the other windows
+	SkipTaskbar     bool   // Whether the window is hidden from the taskbar
+	Kiosk           bool   // Whether the window starts in kiosk mode
+
+	Parent *Window // Parent of the window, which it stays above
+	Modal  bool    // Whether the window is a modal of Parent
+
+	// Limits of the window size. Zero means no limit.
+	MinWidth, MinHeight int
+	MaxWidth, MaxHeight int
+
+	WebPreferences WebPreferences // Settings of the page
+	//	Menu             []MenuEntry
+}
+
+// WebPreferences contains settings of the page in the window.
+type WebPreferences struct {
+	NodeIntegration  bool    // Whether the page can use Node.js. It requires Sandbox disabled
+	Sandbox          *bool   // Whether the page is sandboxed. True when nil
+	ContextIsolation *bool   // Whether the preload script runs in a separated context. True when nil
+	Preload          string  // Absolute path of the script loaded before the page
+	ZoomFactor       float64 // Zoom factor of the page. Zero means 1.0
+}
+
+// FramedWindowOptions contains options for an "ordinary" window with title bar,
+// frame, and min/max/close buttons.
+var FramedWindowOptions = WindowOptions{
+	Shape: Rect{Width: 800, Height: 600, Left: 100, Top: 100},
+	Title: util.ApplicationName,
+}
+
+// Bool returns a pointer to b, for the options of *bool.
+func Bool(b bool) *bool {
+	return &b
+}
+
+// defaultShape fills the size of FramedWindowOptions in r, and its position too if r is zero.
+func defaultShape(r Rect) Rect {
+	def := FramedWindowOptions.Shape
+	if r == (Rect{}) {
+		return def
+	}
+	if r.Width == 0 {
+		r.Width = def.Width
+	}
+	if r.Height == 0 {
+		r.Height = def.Height
+	}
+	return r
+}
+
+// createOptions is WindowOptions sent to the framework, with the defaults filled.
+type createOptions struct {
+	Title            string               `json:"title"`
+	IconPath         string               `json:"icon_path"`
+	Shape            Rect                 `json:"shape"`
+	TitleBar         bool                 `json:"titleBar"`
+	Frame            bool                 `json:"has_frame"`
+	Resizable        bool                 `json:"resizable"`
+	CloseButton      bool                 `json:"closeButton"`
+	MinButton        bool                 `json:"minButton"`
+	FullScreenButton bool                 `json:"maxButton"`
+	Show             bool                 `json:"show"`
+	BackgroundColor  string               `json:"backgroundColor,omitempty"`
+	Transparent      bool                 `json:"transparent"`
+	AlwaysOnTop      bool                 `json:"alwaysOnTop"`
+	SkipTaskbar      bool                 `json:"skipTaskbar"`
+	Kiosk            bool                 `json:"kiosk"`
+	Parent           *Window              `json:"parent,omitempty"`
+	Modal            bool                 `json:"modal"`
+	MinWidth         int                  `json:"minWidth,omitempty"`
+	MinHeight        int                  `json:"minHeight,omitempty"`
+	MaxWidth         int                  `json:"maxWidth,omitempty"`
+	MaxHeight        int                  `json:"maxHeight,omitempty"`
+	WebPreferences   createWebPreferences `json:"webPreferences"`
+}
+
+type createWebPreferences struct {
+	NodeIntegration  bool    `json:"nodeIntegration"`
+	Sandbox          bool    `json:"sandbox"`
+	ContextIsolation bool    `json:"contextIsolation"`
+	Preload          string  `json:"preload,omitempty"`
+	ZoomFactor       float64 `json:"zoomFactor"`
+}
+
+var colorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+
+// createOptions validates opt, and fills the defaults.
+func (opt *WindowOptions) createOptions() (*createOptions, error) {
+	if err := opt.validate(); err != nil {
+		return nil, err
+	}
+	wp := &opt.WebPreferences
+	co := &createOptions{
+		Title:            opt.Title,
+		IconPath:         opt.IconPath,
+		Shape:            defaultShape(opt.Shape),
+		TitleBar:         boolOr(opt.TitleBar, true),
+		Frame:            boolOr(opt.Frame, true),
+		Resizable:        boolOr(opt.Resizable, true),
+		CloseButton:      boolOr(opt.CloseButton, true),
+		MinButton:        boolOr(opt.MinButton, true),
+		FullScreenButton: boolOr(opt.FullScreenButton, true),
+		Show:             boolOr(opt.Show, true),
+		BackgroundColor:  opt.BackgroundColor,
+		Transparent:      opt.Transparent,
+		AlwaysOnTop:      opt.AlwaysOnTop,
+		SkipTaskbar:      opt.SkipTaskbar,
+		Kiosk:            opt.Kiosk,
+		Parent:           opt.Parent,
+		Modal:            opt.Modal,
+		MinWidth:         opt.MinWidth,
+		MinHeight:        opt.MinHeight,
+		MaxWidth:         opt.MaxWidth,
+		MaxHeight:        opt.MaxHeight,
+		WebPreferences: createWebPreferences{
+			NodeIntegration:  wp.NodeIntegration,
+			Sandbox:          boolOr(wp.Sandbox, true),
+			ContextIsolation: boolOr(wp.ContextIsolation, true),
+			Preload:          wp.Preload,
+			ZoomFactor:       wp.ZoomFactor,
+		},
+	}
+	if co.Title == "" {
+		co.Title = util.ApplicationName
+	}
+	if co.WebPreferences.ZoomFactor == 0 {
+		co.WebPreferences.ZoomFactor = 1
+	}
+	return co, nil
+}
+
+// validate returns an apierror.ErrInvalidArgument error if the combination of the options is invalid.
+func (opt *WindowOptions) validate() error {
+	wp := &opt.WebPreferences
+	switch {
+	case opt.Shape.Width < 0 || opt.Shape.Height < 0:
+		return invalidOptions("negative size %dx%d", opt.Shape.Width, opt.Shape.Height)
+	case opt.MinWidth < 0 || opt.MinHeight < 0 || opt.MaxWidth < 0 || opt.MaxHeight < 0:
+		return invalidOptions("negative size limit")
+	case opt.MaxWidth > 0 && opt.MinWidth > opt.MaxWidth:
+		return invalidOptions("MinWidth %d is larger than MaxWidth %d", opt.MinWidth, opt.MaxWidth)
+	case opt.MaxHeight > 0 && opt.MinHeight > opt.MaxHeight:
+		return invalidOptions("MinHeight %d is larger than MaxHeight %d", opt.MinHeight, opt.MaxHeight)
+	case opt.BackgroundColor != "" && !colorPattern.MatchString(opt.BackgroundColor):
+		return invalidOptions("invalid BackgroundColor %q", opt.BackgroundColor)
+	case opt.Transparent && boolOr(opt.Frame, true):
+		return invalidOptions("Transparent requires Frame disabled")
+	case opt.Modal && opt.Parent == nil:
+		return invalidOptions("Modal requires Parent")
+	case wp.NodeIntegration && boolOr(wp.Sandbox, true):
+		return invalidOptions("NodeIntegration requires Sandbox disabled")
+	case wp.Preload != "" && !filepath.IsAbs(wp.Preload):
+		return invalidOptions("Preload %q is not absolute", wp.Preload)
+	case wp.ZoomFactor < 0 || math.IsNaN(wp.ZoomFactor) || math.IsInf(wp.ZoomFactor, 0):
+		return invalidOptions("invalid ZoomFactor %g", wp.ZoomFactor)
+	}
+	if opt.Parent != nil {
+		return opt.Parent.Alive("_create")
+	}
+	return nil
+}
+
+func invalidOptions(format string, args ...interface{}) error {
+	return apierror.New(apierror.CodeInvalidArgument, binding.ObjWindow, binding.ObjStaticID, "_create", fmt.Sprintf(format, args...))
+}
+
+func boolOr(b *bool, def bool) bool {
+	if b == nil {
+		return def
+	}
+	return *b
+}
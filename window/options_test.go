@@ -0,0 +1,72 @@
+package window
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-meson/meson/apierror"
+	"github.com/go-meson/meson/util"
+)
+
+func TestWindowOptionsDefaults(t *testing.T) {
+	co, err := (&WindowOptions{}).createOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !co.Frame || !co.Resizable || !co.TitleBar || !co.Show {
+		t.Errorf("zero options must make an ordinary window: %#v", co)
+	}
+	if !co.WebPreferences.Sandbox || !co.WebPreferences.ContextIsolation || co.WebPreferences.ZoomFactor != 1 {
+		t.Errorf("invalid default web preferences: %#v", co.WebPreferences)
+	}
+	if co.Shape != FramedWindowOptions.Shape || co.Title != util.ApplicationName {
+		t.Errorf("zero options must have the shape and the title of FramedWindowOptions: %#v", co)
+	}
+
+	co, err = (&WindowOptions{Shape: Rect{Left: 10, Top: 20, Width: 300}}).createOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if co.Shape != (Rect{Left: 10, Top: 20, Width: 300, Height: 600}) {
+		t.Errorf("zero height is not filled: %#v", co.Shape)
+	}
+
+	co, err = (&WindowOptions{Frame: Bool(false), Transparent: true}).createOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if co.Frame || !co.Transparent {
+		t.Errorf("options are not applied: %#v", co)
+	}
+}
+
+func TestWindowOptionsValidation(t *testing.T) {
+	parent := newWindow(20)
+	defer parent.Destroyed()
+	tests := []WindowOptions{
+		{Transparent: true},
+		{Modal: true},
+		{MinWidth: 800, MaxWidth: 400},
+		{BackgroundColor: "white"},
+		{WebPreferences: WebPreferences{NodeIntegration: true}},
+		{WebPreferences: WebPreferences{Preload: "preload.js"}},
+		{WebPreferences: WebPreferences{ZoomFactor: -1}},
+	}
+	for _, opt := range tests {
+		if _, err := NewBrowserWindow(&opt); !errors.Is(err, apierror.ErrInvalidArgument) {
+			t.Errorf("%#v must be invalid: %v", opt, err)
+		}
+	}
+
+	co, err := (&WindowOptions{Parent: parent, Modal: true, BackgroundColor: "#80ffffff",
+		WebPreferences: WebPreferences{NodeIntegration: true, Sandbox: Bool(false)}}).createOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(co)
+	if !strings.Contains(string(b), `"parent":{"type":2,"id":20}`) {
+		t.Errorf("parent is not encoded as a reference: %s", b)
+	}
+}
@@ -0,0 +1,125 @@
+package window
+
+import (
+	"context"
+
+	"github.com/go-meson/meson/internal/command"
+)
+
+// Minimize minimizes the window.
+func (w *Window) Minimize() error {
+	return command.Post(&w.Object, "minimize")
+}
+
+// Maximize maximizes the window.
+func (w *Window) Maximize() error {
+	return command.Post(&w.Object, "maximize")
+}
+
+// Unmaximize restores the window from the maximized state.
+func (w *Window) Unmaximize() error {
+	return command.Post(&w.Object, "unmaximize")
+}
+
+// Restore restores the window from the minimized state.
+func (w *Window) Restore() error {
+	return command.Post(&w.Object, "restore")
+}
+
+// SetFullScreen makes the window full screen, or leaves full screen.
+func (w *Window) SetFullScreen(flag bool) error {
+	return command.Post(&w.Object, "setFullScreen", flag)
+}
+
+// IsFullScreen reports whether the window is full screen.
+func (w *Window) IsFullScreen() (bool, error) {
+	return w.IsFullScreenContext(context.Background())
+}
+
+// IsFullScreenContext is same as IsFullScreen, but gives up waiting the framework when ctx is done.
+func (w *Window) IsFullScreenContext(ctx context.Context) (bool, error) {
+	var b bool
+	err := w.call(ctx, "isFullScreen", &b)
+	return b, err
+}
+
+// Focus focuses the window.
+func (w *Window) Focus() error {
+	return command.Post(&w.Object, "focus")
+}
+
+// Blur removes the focus from the window.
+func (w *Window) Blur() error {
+	return command.Post(&w.Object, "blur")
+}
+
+// Show shows and focuses the window.
+func (w *Window) Show() error {
+	return command.Post(&w.Object, "show")
+}
+
+// ShowInactive shows the window without focusing it.
+func (w *Window) ShowInactive() error {
+	return command.Post(&w.Object, "showInactive")
+}
+
+// Hide hides the window.
+func (w *Window) Hide() error {
+	return command.Post(&w.Object, "hide")
+}
+
+// IsVisible reports whether the window is visible.
+func (w *Window) IsVisible() (bool, error) {
+	return w.IsVisibleContext(context.Background())
+}
+
+// IsVisibleContext is same as IsVisible, but gives up waiting the framework when ctx is done.
+func (w *Window) IsVisibleContext(ctx context.Context) (bool, error) {
+	var b bool
+	err := w.call(ctx, "isVisible", &b)
+	return b, err
+}
+
+// IsFocused reports whether the window is focused.
+func (w *Window) IsFocused() (bool, error) {
+	return w.IsFocusedContext(context.Background())
+}
+
+// IsFocusedContext is same as IsFocused, but gives up waiting the framework when ctx is done.
+func (w *Window) IsFocusedContext(ctx context.Context) (bool, error) {
+	var b bool
+	err := w.call(ctx, "isFocused", &b)
+	return b, err
+}
+
+// SetAlwaysOnTop keeps the window above the other windows, or stops it.
+func (w *Window) SetAlwaysOnTop(flag bool) error {
+	return command.Post(&w.Object, "setAlwaysOnTop", flag)
+}
+
+// SetTitle changes the title of the window.
+func (w *Window) SetTitle(title string) error {
+	return command.Post(&w.Object, "setTitle", title)
+}
+
+// Title returns the title of the window.
+func (w *Window) Title() (string, error) {
+	return w.TitleContext(context.Background())
+}
+
+// TitleContext is same as Title, but gives up waiting the framework when ctx is done.
+func (w *Window) TitleContext(ctx context.Context) (string, error) {
+	var title string
+	err := w.call(ctx, "getTitle", &title)
+	return title, err
+}
+
+// SetResizable sets whether the user can resize the window.
+func (w *Window) SetResizable(flag bool) error {
+	return command.Post(&w.Object, "setResizable", flag)
+}
+
+// SetEnabled enables or disables the window. A disabled window ignores the user input.
+func (w *Window) SetEnabled(flag bool) error {
+	return command.Post(&w.Object, "setEnabled", flag)
+}
@@ -1,14 +1,23 @@
+// Package window creates and controls browser windows.
+//
+// The setters of a window, such as SetBounds, SetTitle and Show, send the command without waiting the framework.
+// They return the failures found before sending, such as an invalid argument or a destroyed window,
+// but not a failure in the framework; read the state back, such as by Bounds, to check the result.
+// Unlike synchronous calls, they can be made from any event handler.
 package window
 
 import (
-	"encoding/json"
-	"errors"
+	"context"
+	"github.com/go-meson/meson/apierror"
 	evt "github.com/go-meson/meson/event"
 	"github.com/go-meson/meson/internal/binding"
+	"github.com/go-meson/meson/internal/codec"
 	"github.com/go-meson/meson/internal/command"
 	"github.com/go-meson/meson/internal/event"
 	"github.com/go-meson/meson/internal/object"
-	"github.com/go-meson/meson/util"
+	obj "github.com/go-meson/meson/object"
+	"github.com/go-meson/meson/webcontents"
+	"log"
 )
 
 // Rect represents a rectangular region on the screen
@@ -24,112 +33,353 @@ type Window struct {
 }
 
 func newWindow(id int64) *Window {
-	win := &Window{Object: object.NewObject(id, binding.ObjWindow)}
-	object.AddObject(binding.ObjWindow, id, win)
-	// register default handler
-	return win
-}
-
-// WindowOptions contains options for creating windows
-type WindowOptions struct {
-	Title            string `json:"title"` // String to display in title bar
-	IconPath         string `json:"icon_path"`
-	Shape            Rect   `json:"shape"`       // Initial size and position of window
-	TitleBar         bool   `json:"titleBar"`    // Whether the window title bar
-	Frame            bool   `json:"has_frame"`   // Whether the window has a frame
-	Resizable        bool   `json:"resizable"`   // Whether the window border can be dragged to change its shape
-	CloseButton      bool   `json:"closeButton"` // Whether the window has a close button
-	MinButton        bool   `json:"minButton"`   // Whether the window has a miniaturize button
-	FullScreenButton bool   `json:"maxButton"`   // Whether the window has a full screen button
-	//	Menu             []MenuEntry
-}
-
-// FramedWindowOptions contains options for an "ordinary" window with title bar,
-// frame, and min/max/close buttons.
-var FramedWindowOptions = WindowOptions{
-	Shape:            Rect{Width: 800, Height: 600, Left: 100, Top: 100},
-	TitleBar:         true,
-	Frame:            true,
-	Resizable:        true,
-	CloseButton:      true,
-	MinButton:        true,
-	FullScreenButton: true,
-	Title:            util.ApplicationName,
+	w, _ := loadWindow(id)
+	return w
+}
+
+// loadWindow returns the window of id, and reports whether its wrapper is made now.
+func loadWindow(id int64) (*Window, bool) {
+	created := false
+	// the window may be registered already by Attach, when an event about it comes first.
+	o := object.LoadOrAddObject(binding.ObjWindow, id, func() object.ObjectRefInternal {
+		created = true
+		return &Window{Object: object.NewObject(id, binding.ObjWindow)}
+	})
+	return o.(*Window), created
+}
+
+// watchClosed destroys the wrapper when the window is closed,
+// after the handlers of 'closed' are called.
+func (w *Window) watchClosed() {
+	if _, err := event.AddCallback(&w.Object, "closed", closedObserver{w: w}); err != nil {
+		log.Printf("watch 'closed' of window %d fail: %v", w.Id, err)
+	}
+}
+
+type closedObserver struct {
+	w *Window
+}
+
+func (c closedObserver) Call(obj.ObjectRef, codec.Raw) (bool, error) {
+	return false, nil
+}
+
+func (c closedObserver) Observe(obj.ObjectRef, codec.Raw, bool) {
+	c.w.Destroyed()
 }
 
 // NewBrowserWindow Create and control browser windows.
+// opt is validated before the window is created; nil means the default options.
 func NewBrowserWindow(opt *WindowOptions) (*Window, error) {
-	if !command.APIReady {
-		return nil, errors.New("meson api is not ready yet")
+	return NewBrowserWindowContext(context.Background(), opt)
+}
+
+// NewBrowserWindowContext is same as NewBrowserWindow, but gives up waiting the framework when ctx is done.
+func NewBrowserWindowContext(ctx context.Context, opt *WindowOptions) (*Window, error) {
+	if opt == nil {
+		opt = &WindowOptions{}
+	}
+	co, err := opt.createOptions()
+	if err != nil {
+		return nil, err
+	}
+	cmd := command.MakeCreateCommand(binding.ObjWindow, co)
+	if err := command.CheckReady(cmd.Type, cmd.Method); err != nil {
+		return nil, err
 	}
-	cmd := command.MakeCreateCommand(binding.ObjWindow, opt)
 
-	response, err := command.SendMessage(&cmd)
+	response, err := command.SendMessageContext(ctx, &cmd)
 	if err != nil {
 		return nil, err
 	}
 
 	var cr command.CreateRespResult
-	if err := json.Unmarshal(response, &cr); err != nil {
+	if err := codec.Decode(response, &cr); err != nil {
 		return nil, err
 	}
 
-	return newWindow(cr.ID), nil
+	w, created := loadWindow(cr.ID)
+	if created {
+		w.watchClosed()
+	}
+	return w, nil
 }
 
-//LoadURLOptions is optional parameter for Window.LoadURL and WebContents.LoadURL
-type LoadURLOptions struct {
-	HTTPReferer  string `json:"httpReferrer"` // A HTTP Referrer url.
-	UserAgent    string `json:"userAgent"`    // A user agent originating the request.
-	ExtraHeaders string `json:"extraHeaders"` // Extra headers separated by “\n”
+// All returns the live windows, in the order of creation.
+func All() []*Window {
+	objs := object.Objects(binding.ObjWindow)
+	wins := make([]*Window, 0, len(objs))
+	for _, o := range objs {
+		if w, ok := o.(*Window); ok {
+			wins = append(wins, w)
+		}
+	}
+	return wins
+}
+
+// FromID returns the live window of id, or nil if there is no such window.
+func FromID(id int64) *Window {
+	w, _ := object.GetObject(binding.ObjWindow, id).(*Window)
+	return w
 }
 
+// Attach returns the window of id, which the framework has just created.
+// Unlike FromID, it makes the wrapper of a window unknown to Go, such as the one opened by a page.
+func Attach(id int64) *Window {
+	w, created := loadWindow(id)
+	if created {
+		// Attach is called from event handlers, where a synchronous call may not be made.
+		go w.watchClosed()
+	}
+	return w
+}
+
+// Focused returns the focused window of the application, or nil if no window is focused.
+func Focused() (*Window, error) {
+	return FocusedContext(context.Background())
+}
+
+// FocusedContext is same as Focused, but gives up waiting the framework when ctx is done.
+func FocusedContext(ctx context.Context) (*Window, error) {
+	cmd := command.MakeCallCommand(binding.ObjWindow, binding.ObjStaticID, "getFocusedWindow")
+	r, err := command.SendMessageContext(ctx, &cmd)
+	if err != nil {
+		return nil, err
+	}
+	var ref object.Ref
+	if err := codec.Decode(r, &ref); err != nil {
+		return nil, err
+	}
+	if ref.Type != binding.ObjWindow {
+		return nil, nil
+	}
+	// the focused window may be opened by a page, and unknown to Go yet.
+	return Attach(ref.ID), nil
+}
+
+//LoadURLOptions is optional parameter for Window.LoadURL and WebContents.LoadURL
+type LoadURLOptions = webcontents.LoadURLOptions
+
 //LoadURL is same as WebContents.LoadURL
 func (w *Window) LoadURL(url string) error {
-	cmd := command.MakeCallCommand(w.ObjType, w.Id, "loadURL", url)
-	return command.PostMessage(&cmd)
+	return command.Post(&w.Object, "loadURL", url)
 }
 
 func (w *Window) LoadURLWithOptions(url string, opt *LoadURLOptions) error {
-	cmd := command.MakeCallCommand(w.ObjType, w.Id, "loadURL", opt)
-	return command.PostMessage(&cmd)
+	return command.Post(&w.Object, "loadURL", url, opt)
+}
+
+// WebContents returns the page in the window.
+func (w *Window) WebContents() (*webcontents.WebContents, error) {
+	return w.WebContentsContext(context.Background())
+}
+
+// WebContentsContext is same as WebContents, but gives up waiting the framework when ctx is done.
+func (w *Window) WebContentsContext(ctx context.Context) (*webcontents.WebContents, error) {
+	var ref object.Ref
+	if err := w.call(ctx, "getWebContents", &ref); err != nil {
+		return nil, err
+	}
+	if ref.Type != binding.ObjWebContents {
+		return nil, apierror.New(apierror.CodeUnknown, w.ObjType, w.Id, "getWebContents", "no web contents")
+	}
+	wc := webcontents.Attach(ref.ID)
+	w.AddDependent(wc)
+	return wc, nil
 }
 
+// Close tries to close the window, as the user clicks the close button.
+// The window is destroyed after it is closed, unless the close is prevented.
 func (w *Window) Close() {
-	cmd := command.MakeCallCommand(w.ObjType, w.Id, "close")
-	command.PostMessage(&cmd)
+	command.Post(&w.Object, "close")
+}
+
+// Destroy destroys the window immediately, without emitting close event.
+//
+// Methods of a destroyed window, and of its WebContents, return an apierror.ErrObjectDestroyed error.
+// It is so also after the window is closed.
+func (w *Window) Destroy() error {
+	return command.Destroy(&w.Object)
 }
 
 func (w *Window) OpenDevTool() {
 	// TODO: options??
-	cmd := command.MakeCallCommand(w.ObjType, w.Id, "openDevTools")
-	if err := command.PostMessage(&cmd); err != nil {
+	if err := command.Post(&w.Object, "openDevTools"); err != nil {
 		panic(err)
 	}
 }
 
 func (w *Window) CloseDevTool() {
-	cmd := command.MakeCallCommand(w.ObjType, w.Id, "closeDevTools")
-	command.PostMessage(&cmd)
+	command.Post(&w.Object, "closeDevTools")
 }
 
 func (w *Window) IsDevToolOpened() bool {
-	cmd := command.MakeCallCommand(w.ObjType, w.Id, "isDevToolsOpened")
-	r, err := command.SendMessage(&cmd)
+	b, err := w.IsDevToolOpenedContext(context.Background())
 	if err != nil {
 		return false
 	}
+	return b
+}
+
+// IsDevToolOpenedContext reports whether the developer tools are opened, or returns error when ctx is done.
+func (w *Window) IsDevToolOpenedContext(ctx context.Context) (bool, error) {
 	var b bool
-	if err := json.Unmarshal(r, &b); err != nil {
-		return false
+	err := w.call(ctx, "isDevToolsOpened", &b)
+	return b, err
+}
+
+// call calls method of the window, and decodes the result into v.
+func (w *Window) call(ctx context.Context, method string, v interface{}, args ...interface{}) error {
+	r, err := command.Call(ctx, &w.Object, method, args...)
+	if err != nil {
+		return err
 	}
-	return b
+	return codec.Decode(r, v)
 }
 
 //------------------------------------------------------------------------
 // Callbacks
 
-func (w *Window) OnWindowClose(callback evt.CommonPreventableCallbackHandler) {
+func (w *Window) OnWindowClose(callback evt.CommonPreventableCallbackHandler) (*evt.Subscription, error) {
 	const en = "close"
-	event.AddCallback(&w.Object, en, event.CommonPreventableCallbackItem{F: callback})
+	return event.AddCallback(&w.Object, en, event.CommonPreventableCallbackItem{F: callback})
+}
+
+// OnClosed set 'closed' event handler.
+//
+// 'closed' emitted when the window is closed. The window is destroyed after that.
+func (w *Window) OnClosed(callback func(w *Window)) (*evt.Subscription, error) {
+	const en = "closed"
+	return w.subscribe(en, callback)
+}
+
+// OnReadyToShow set 'ready-to-show' event handler.
+//
+// 'ready-to-show' emitted when the page has been rendered, and the window can be shown without a visual flash.
+func (w *Window) OnReadyToShow(callback func(w *Window)) (*evt.Subscription, error) {
+	const en = "ready-to-show"
+	return w.subscribe(en, callback)
+}
+
+// OnFocus set 'focus' event handler.
+//
+// 'focus' emitted when the window gets focused.
+func (w *Window) OnFocus(callback func(w *Window)) (*evt.Subscription, error) {
+	const en = "focus"
+	return w.subscribe(en, callback)
+}
+
+// OnBlur set 'blur' event handler.
+//
+// 'blur' emitted when the window loses focus.
+func (w *Window) OnBlur(callback func(w *Window)) (*evt.Subscription, error) {
+	const en = "blur"
+	return w.subscribe(en, callback)
+}
+
+// OnShow set 'show' event handler.
+//
+// 'show' emitted when the window is shown.
+func (w *Window) OnShow(callback func(w *Window)) (*evt.Subscription, error) {
+	const en = "show"
+	return w.subscribe(en, callback)
+}
+
+// OnHide set 'hide' event handler.
+//
+// 'hide' emitted when the window is hidden.
+func (w *Window) OnHide(callback func(w *Window)) (*evt.Subscription, error) {
+	const en = "hide"
+	return w.subscribe(en, callback)
+}
+
+// OnMaximize set 'maximize' event handler.
+//
+// 'maximize' emitted when the window is maximized.
+func (w *Window) OnMaximize(callback func(w *Window)) (*evt.Subscription, error) {
+	const en = "maximize"
+	return w.subscribe(en, callback)
+}
+
+// OnUnmaximize set 'unmaximize' event handler.
+//
+// 'unmaximize' emitted when the window leaves the maximized state.
+func (w *Window) OnUnmaximize(callback func(w *Window)) (*evt.Subscription, error) {
+	const en = "unmaximize"
+	return w.subscribe(en, callback)
+}
+
+// OnMinimize set 'minimize' event handler.
+//
+// 'minimize' emitted when the window is minimized.
+func (w *Window) OnMinimize(callback func(w *Window)) (*evt.Subscription, error) {
+	const en = "minimize"
+	return w.subscribe(en, callback)
+}
+
+// OnRestore set 'restore' event handler.
+//
+// 'restore' emitted when the window is restored from the minimized state.
+func (w *Window) OnRestore(callback func(w *Window)) (*evt.Subscription, error) {
+	const en = "restore"
+	return w.subscribe(en, callback)
+}
+
+// OnResize set 'resize' event handler.
+//
+// 'resize' emitted with the new bounds after the window is resized.
+func (w *Window) OnResize(callback func(w *Window, bounds Rect)) (*evt.Subscription, error) {
+	const en = "resize"
+	return w.subscribeBounds(en, callback)
+}
+
+// OnMove set 'move' event handler.
+//
+// 'move' emitted with the new bounds after the window is moved.
+func (w *Window) OnMove(callback func(w *Window, bounds Rect)) (*evt.Subscription, error) {
+	const en = "move"
+	return w.subscribeBounds(en, callback)
+}
+
+// OnEnterFullScreen set 'enter-full-screen' event handler.
+//
+// 'enter-full-screen' emitted when the window enters full screen.
+func (w *Window) OnEnterFullScreen(callback func(w *Window)) (*evt.Subscription, error) {
+	const en = "enter-full-screen"
+	return w.subscribe(en, callback)
+}
+
+// OnLeaveFullScreen set 'leave-full-screen' event handler.
+//
+// 'leave-full-screen' emitted when the window leaves full screen.
+func (w *Window) OnLeaveFullScreen(callback func(w *Window)) (*evt.Subscription, error) {
+	const en = "leave-full-screen"
+	return w.subscribe(en, callback)
+}
+
+// OnPageTitleUpdated set 'page-title-updated' event handler.
+//
+// 'page-title-updated' emitted when the page changes its title.
+// Returning true keeps the window title from following it.
+func (w *Window) OnPageTitleUpdated(callback func(w *Window, title string) bool) (*evt.Subscription, error) {
+	const en = "page-title-updated"
+	return evt.Subscribe(w, en, func(sender obj.ObjectRef, p struct {
+		Title string `json:"title"`
+	}) bool {
+		return callback(w, p.Title)
+	})
+}
+
+func (w *Window) subscribe(name string, callback func(w *Window)) (*evt.Subscription, error) {
+	return evt.Subscribe(w, name, func(sender obj.ObjectRef) {
+		callback(w)
+	})
+}
+
+func (w *Window) subscribeBounds(name string, callback func(w *Window, bounds Rect)) (*evt.Subscription, error) {
+	return evt.Subscribe(w, name, func(sender obj.ObjectRef, p struct {
+		Bounds Rect `json:"bounds"`
+	}) {
+		callback(w, p.Bounds)
+	})
 }
@@ -0,0 +1,484 @@
+package window
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-meson/meson/apierror"
+	"github.com/go-meson/meson/internal/binding"
+	"github.com/go-meson/meson/internal/codec"
+	"github.com/go-meson/meson/internal/command"
+	"github.com/go-meson/meson/internal/object"
+	obj "github.com/go-meson/meson/object"
+	"github.com/go-meson/meson/transport"
+)
+
+// serveLoopback answers every request from lb with result.
+func serveLoopback(t *testing.T, lb *transport.Loopback, result func(cmd *command.Command) string) {
+	go func() {
+		for req := range lb.Requests() {
+			var cmd command.Command
+			if err := json.Unmarshal(req, &cmd); err != nil {
+				t.Errorf("invalid request: %s", req)
+				continue
+			}
+			if cmd.ActionID == 0 {
+				continue
+			}
+			resp := command.Response{
+				Action:   binding.ActReply,
+				ActionID: cmd.ActionID,
+				Type:     cmd.Type,
+				ID:       cmd.ID,
+				Result:   codec.Raw(result(&cmd)),
+			}
+			b, _ := json.Marshal(&resp)
+			if _, err := lb.Post(0, b, false); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+}
+
+func TestWindowLoopback(t *testing.T) {
+	lb := transport.NewLoopback()
+	command.SetTransport(lb)
+	serveLoopback(t, lb, func(cmd *command.Command) string {
+		switch cmd.Method {
+		case "_create":
+			return `{"_type":2,"_id":10}`
+		case "isDevToolsOpened":
+			return `true`
+		}
+		return `null`
+	})
+	lb.SetReady()
+	<-command.Ready()
+
+	win, err := NewBrowserWindow(&FramedWindowOptions)
+	if err != nil {
+		t.Fatalf("NewBrowserWindow fail: %v", err)
+	}
+	if win.Id != 10 {
+		t.Errorf("invalid window id: %d", win.Id)
+	}
+	if !win.IsDevToolOpened() {
+		t.Error("IsDevToolOpened returns false")
+	}
+
+	if err := win.Destroy(); err != nil {
+		t.Fatalf("Destroy fail: %v", err)
+	}
+	if object.GetObject(binding.ObjWindow, 10) != nil {
+		t.Error("destroyed window is still registered")
+	}
+	if _, err := win.IsDevToolOpenedContext(context.Background()); !errors.Is(err, apierror.ErrObjectDestroyed) {
+		t.Errorf("unexpected error for destroyed window: %v", err)
+	}
+}
+
+func TestWindowDestroyedByFramework(t *testing.T) {
+	win := newWindow(11)
+	resp := command.Response{Action: binding.ActDelete, Type: binding.ObjWindow, ID: 11}
+	b, _ := json.Marshal(&resp)
+	lb := transport.NewLoopback()
+	command.SetTransport(lb)
+	if _, err := lb.Post(0, b, false); err != nil {
+		t.Fatal(err)
+	}
+	if !win.IsDestroyed() || object.GetObject(binding.ObjWindow, 11) != nil {
+		t.Error("window is not destroyed")
+	}
+}
+
+func TestWindowCloseSubscription(t *testing.T) {
+	lb := transport.NewLoopback()
+	command.SetTransport(lb)
+	regs := make(chan command.Command, 4)
+	serveLoopback(t, lb, func(cmd *command.Command) string {
+		if cmd.Method == "_regevent" {
+			regs <- *cmd
+			return `5`
+		}
+		return `null`
+	})
+	lb.SetReady()
+	<-command.Ready()
+
+	win := newWindow(12)
+	defer win.Destroyed()
+	calls := 0
+	handler := func(obj.ObjectRef) bool {
+		calls++
+		return false
+	}
+	s1, err := win.OnWindowClose(handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, err := win.OnWindowClose(handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regs) != 1 {
+		t.Fatalf("event is registered %d times", len(regs))
+	}
+	<-regs
+
+	emit := func() {
+		evt := command.Response{Action: binding.ActEvent, Type: binding.ObjWindow, ID: 12, EventID: 5}
+		b, _ := json.Marshal(&evt)
+		if _, err := lb.Post(0, b, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	emit()
+	if calls != 2 {
+		t.Errorf("handlers are called %d times", calls)
+	}
+	s1.Unsubscribe()
+	s1.Unsubscribe()
+	emit()
+	if calls != 3 {
+		t.Errorf("unsubscribed handler is called: %d", calls)
+	}
+	if win.EventHandlers(5) == nil {
+		t.Error("remaining handler is removed")
+	}
+	s2.Unsubscribe()
+	if win.EventHandlers(5) != nil {
+		t.Error("handler is not removed")
+	}
+}
+
+func TestWindowLookup(t *testing.T) {
+	lb := transport.NewLoopback()
+	command.SetTransport(lb)
+	serveLoopback(t, lb, func(cmd *command.Command) string {
+		if cmd.Method == "getFocusedWindow" {
+			return fmt.Sprintf(`{"type":%d,"id":21}`, binding.ObjWindow)
+		}
+		return `null`
+	})
+	lb.SetReady()
+	<-command.Ready()
+
+	w20, w21 := newWindow(20), newWindow(21)
+	defer w20.Destroyed()
+	defer w21.Destroyed()
+	all := All()
+	if len(all) < 2 || all[len(all)-2] != w20 || all[len(all)-1] != w21 {
+		t.Errorf("unexpected windows: %v", all)
+	}
+	if FromID(20) != w20 || FromID(999) != nil {
+		t.Error("FromID returns a wrong window")
+	}
+	focused, err := Focused()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if focused != w21 {
+		t.Errorf("unexpected focused window: %v", focused)
+	}
+
+	var o object.Object
+	if err := json.Unmarshal([]byte(fmt.Sprintf(`{"type":%d,"id":20}`, binding.ObjWindow)), &o); err != nil {
+		t.Fatal(err)
+	}
+	o.Destroyed()
+	if !w20.IsDestroyed() || FromID(20) != nil {
+		t.Error("decoded object does not share the state of the window")
+	}
+}
+
+func TestWindowFocusedUnknown(t *testing.T) {
+	lb := transport.NewLoopback()
+	command.SetTransport(lb)
+	watched := make(chan struct{}, 1)
+	serveLoopback(t, lb, func(cmd *command.Command) string {
+		switch cmd.Method {
+		case "getFocusedWindow":
+			return fmt.Sprintf(`{"type":%d,"id":23}`, binding.ObjWindow)
+		case "_regevent":
+			watched <- struct{}{}
+			return `1`
+		}
+		return `null`
+	})
+	lb.SetReady()
+	<-command.Ready()
+
+	focused, err := Focused()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if focused == nil || focused.Id != 23 || FromID(23) != focused {
+		t.Fatalf("window opened by a page is not attached: %v", focused)
+	}
+	defer focused.Destroyed()
+	// the window watches 'closed' in the background; wait it not to leak into the next test.
+	<-watched
+}
+
+func TestWindowGeometry(t *testing.T) {
+	lb := transport.NewLoopback()
+	command.SetTransport(lb)
+	serveLoopback(t, lb, func(cmd *command.Command) string {
+		if cmd.Method == "getBounds" {
+			return `{"width":320,"height":240,"left":10,"top":20}`
+		}
+		return `null`
+	})
+	lb.SetReady()
+	<-command.Ready()
+
+	win := newWindow(14)
+	defer win.Destroyed()
+	r, err := win.Bounds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != (Rect{Width: 320, Height: 240, Left: 10, Top: 20}) {
+		t.Errorf("invalid bounds: %#v", r)
+	}
+	if err := win.SetSize(-1, 100); !errors.Is(err, apierror.ErrInvalidArgument) {
+		t.Errorf("negative size must be invalid: %v", err)
+	}
+	if err := win.SetAspectRatio(16.0 / 9); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWindowState(t *testing.T) {
+	lb := transport.NewLoopback()
+	command.SetTransport(lb)
+	serveLoopback(t, lb, func(cmd *command.Command) string {
+		switch cmd.Method {
+		case "getTitle":
+			return `"Editor"`
+		case "isFullScreen":
+			return `true`
+		}
+		return `null`
+	})
+	lb.SetReady()
+	<-command.Ready()
+
+	win := newWindow(15)
+	defer win.Destroyed()
+	if title, err := win.Title(); err != nil || title != "Editor" {
+		t.Errorf("Title = %q, %v", title, err)
+	}
+	if fs, err := win.IsFullScreen(); err != nil || !fs {
+		t.Errorf("IsFullScreen = %v, %v", fs, err)
+	}
+	win.Destroyed()
+	if err := win.Minimize(); !errors.Is(err, apierror.ErrObjectDestroyed) {
+		t.Errorf("unexpected error for destroyed window: %v", err)
+	}
+	if _, err := win.IsVisible(); !errors.Is(err, apierror.ErrObjectDestroyed) {
+		t.Errorf("unexpected error for destroyed window: %v", err)
+	}
+}
+
+func TestWindowLoadURLWithOptions(t *testing.T) {
+	lb := transport.NewLoopback()
+	command.SetTransport(lb)
+	loaded := make(chan string, 1)
+	go func() {
+		// loadURL is posted without an action, so it is not answered.
+		for req := range lb.Requests() {
+			var cmd command.Command
+			if json.Unmarshal(req, &cmd) == nil && cmd.Method == "loadURL" {
+				b, _ := json.Marshal(cmd.Args)
+				loaded <- string(b)
+			}
+		}
+	}()
+	lb.SetReady()
+	<-command.Ready()
+
+	win := newWindow(22)
+	defer win.Destroyed()
+	if err := win.LoadURLWithOptions("https://example.com/", &LoadURLOptions{UserAgent: "meson"}); err != nil {
+		t.Fatal(err)
+	}
+	var args []json.RawMessage
+	if err := json.Unmarshal([]byte(<-loaded), &args); err != nil || len(args) != 2 {
+		t.Fatalf("invalid loadURL arguments: %v", args)
+	}
+	var url string
+	var opt LoadURLOptions
+	if json.Unmarshal(args[0], &url) != nil || json.Unmarshal(args[1], &opt) != nil || url != "https://example.com/" || opt.UserAgent != "meson" {
+		t.Errorf("invalid loadURL arguments: %s, %s", args[0], args[1])
+	}
+}
+
+func TestWindowResizeEvent(t *testing.T) {
+	lb := transport.NewLoopback()
+	command.SetTransport(lb)
+	serveLoopback(t, lb, func(cmd *command.Command) string {
+		if cmd.Method == "_regevent" {
+			return `6`
+		}
+		return `null`
+	})
+	lb.SetReady()
+	<-command.Ready()
+
+	win := newWindow(16)
+	defer win.Destroyed()
+	resized := make(chan Rect, 1)
+	s, err := win.OnResize(func(w *Window, bounds Rect) {
+		if w != win {
+			t.Errorf("unexpected sender: %#v", w)
+		}
+		resized <- bounds
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Unsubscribe()
+
+	evt := command.Response{Action: binding.ActEvent, Type: binding.ObjWindow, ID: 16, EventID: 6,
+		Result: codec.Raw(`{"bounds":{"width":640,"height":480,"left":0,"top":0}}`)}
+	b, _ := json.Marshal(&evt)
+	if _, err := lb.Post(0, b, false); err != nil {
+		t.Fatal(err)
+	}
+	if r := <-resized; r != (Rect{Width: 640, Height: 480}) {
+		t.Errorf("invalid bounds: %#v", r)
+	}
+}
+
+func TestWindowWebContents(t *testing.T) {
+	lb := transport.NewLoopback()
+	command.SetTransport(lb)
+	serveLoopback(t, lb, func(cmd *command.Command) string {
+		switch cmd.Method {
+		case "getWebContents":
+			return fmt.Sprintf(`{"type":%d,"id":40}`, binding.ObjWebContents)
+		case "executeJavaScript":
+			return `{"answer":42}`
+		case "getURL":
+			return `"https://example.com/"`
+		}
+		return `null`
+	})
+	lb.SetReady()
+	<-command.Ready()
+
+	win := newWindow(17)
+	defer win.Destroyed()
+	wc, err := win.WebContents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wc.Destroyed()
+	if wc.Id != 40 {
+		t.Errorf("invalid web contents id: %d", wc.Id)
+	}
+	if again, _ := win.WebContents(); again != wc {
+		t.Error("web contents is not shared")
+	}
+	if url, err := wc.URL(); err != nil || url != "https://example.com/" {
+		t.Errorf("URL = %q, %v", url, err)
+	}
+	v, err := wc.ExecuteJavaScript(context.Background(), "({answer: 6 * 7})")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := v.(map[string]interface{}); !ok || m["answer"] != float64(42) {
+		t.Errorf("invalid result: %#v", v)
+	}
+}
+
+func TestWindowClosed(t *testing.T) {
+	lb := transport.NewLoopback()
+	command.SetTransport(lb)
+	serveLoopback(t, lb, func(cmd *command.Command) string {
+		switch cmd.Method {
+		case "_create":
+			return `{"_type":2,"_id":18}`
+		case "_regevent":
+			return `7`
+		case "getWebContents":
+			return fmt.Sprintf(`{"type":%d,"id":41}`, binding.ObjWebContents)
+		}
+		return `null`
+	})
+	lb.SetReady()
+	<-command.Ready()
+
+	win, err := NewBrowserWindow(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer win.Destroyed()
+	wc, err := win.WebContents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := false
+	if _, err := win.OnClosed(func(w *Window) {
+		closed = true
+		if w.IsDestroyed() {
+			t.Error("window is destroyed before 'closed' handlers")
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	evt := command.Response{Action: binding.ActEvent, Type: binding.ObjWindow, ID: 18, EventID: 7}
+	b, _ := json.Marshal(&evt)
+	if _, err := lb.Post(8, b, true); err != nil {
+		t.Fatal(err)
+	}
+	if !closed {
+		t.Error("'closed' handler is not called")
+	}
+	if FromID(18) != nil || !win.IsDestroyed() {
+		t.Error("closed window is not destroyed")
+	}
+	if err := win.SetTitle("stale"); !errors.Is(err, apierror.ErrObjectDestroyed) {
+		t.Errorf("closed window accepts a command: %v", err)
+	}
+	if err := wc.Reload(); !errors.Is(err, apierror.ErrObjectDestroyed) {
+		t.Errorf("web contents of a closed window accepts a command: %v", err)
+	}
+}
+
+func TestNewBrowserWindowNilOptions(t *testing.T) {
+	lb := transport.NewLoopback()
+	command.SetTransport(lb)
+	created := make(chan string, 1)
+	serveLoopback(t, lb, func(cmd *command.Command) string {
+		if cmd.Method == "_create" {
+			b, _ := json.Marshal(cmd.Args)
+			created <- string(b)
+			return `{"_type":2,"_id":19}`
+		}
+		return `null`
+	})
+	lb.SetReady()
+	<-command.Ready()
+
+	win, err := NewBrowserWindow(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer win.Destroyed()
+	var args []struct {
+		Title string `json:"title"`
+		Shape Rect   `json:"shape"`
+	}
+	if err := json.Unmarshal([]byte(<-created), &args); err != nil || len(args) != 1 {
+		t.Fatalf("invalid create arguments: %v", err)
+	}
+	if args[0].Shape != FramedWindowOptions.Shape || args[0].Title != FramedWindowOptions.Title {
+		t.Errorf("nil options are not filled: %#v", args[0])
+	}
+}